@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+)
+
+var errModerateSelf = errors.New("admins can't change their own role, ban or deactivate themselves")
+
+// AdminUser is a user as moderators see it, including the ban that is
+// hidden from everybody else.
+type AdminUser struct {
+	*store.User
+	Ban *store.Ban `json:"ban"`
+}
+
+type UpdateRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+type BanUserPayload struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	// ExpiresIn is the length of the ban in hours, bans are permanent
+	// without it
+	ExpiresIn int `json:"expires_in" validate:"omitempty,min=1"`
+}
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.PaginatedUsersQuery{
+		Limit: 20,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	scope := cursorScope("admin/users", 0, "")
+
+	q.After, err = app.decodeKeysetCursor(q.Cursor, scope)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	users, next, err := app.store.Users.List(r.Context(), q)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	result := make([]AdminUser, len(users))
+	for i := range users {
+		result[i] = AdminUser{User: &users[i], Ban: users[i].Ban}
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, result, next, scope); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateRolePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, ok := app.readModeratedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.SetRole(ctx, userID, payload.Role); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditRoleChanged)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BanUserPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, ok := app.readModeratedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var until *time.Time
+	if payload.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(payload.ExpiresIn) * time.Hour)
+		until = &t
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.Ban(ctx, userID, payload.Reason, until); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditUserBanned)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) unbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.readModeratedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.Unban(ctx, userID); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditUserUnbanned)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deactivateUserHandler disables an account until it is reactivated, e.g.
+// on its owner's request. Unlike a ban it leaves no reason behind.
+func (app *application) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.readModeratedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.Deactivate(ctx, userID); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditDeactivated)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := app.readModeratedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.Reactivate(ctx, userID); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditReactivated)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forceLogoutHandler signs a user out of every session, e.g. after their
+// account was compromised. Unlike the other moderations it doesn't go
+// through readModeratedUserID: admins may sign themselves out too, it
+// locks nobody out for longer than the next login.
+func (app *application) forceLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := readUserIDParam(r, "userID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Users.RevokeSessions(ctx, userID); err != nil {
+		app.moderationErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateUser(ctx, userID)
+	app.audit(r, &userID, "", store.AuditForcedLogout)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readModeratedUserID reads the user a moderation applies to. Admins can't
+// demote, ban or deactivate themselves, which could leave nobody to undo it.
+func (app *application) readModeratedUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := readUserIDParam(r, "userID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return 0, false
+	}
+
+	if userID == getUserFromContext(r).ID {
+		app.badRequestResponse(w, r, errModerateSelf)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func (app *application) moderationErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case store.ErrNotFound:
+		app.notFoundResponse(w, r, err)
+	case store.ErrRoleNotFound:
+		app.badRequestResponse(w, r, err)
+	default:
+		app.statusInternalServerError(w, r, err)
+	}
+}
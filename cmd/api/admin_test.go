@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAdminUsers(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("should forbid listing users without the permission", func(t *testing.T) {
+		mux := app.mount()
+
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": int64(1),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/admin/users", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		checkResponseCode(t, http.StatusForbidden, executeRequest(req, mux).Code)
+	})
+
+	t.Run("should not let admins ban themselves", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"reason": "spam"}`))
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("userID", "1")
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, userCtx, &store.User{ID: 1})
+
+		rr := executeRequest(req.WithContext(ctx), http.HandlerFunc(app.banUserHandler))
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should moderate users", func(t *testing.T) {
+		mux := app.mount()
+
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": int64(store.MockAdminUserID),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		self := strconv.Itoa(store.MockAdminUserID)
+		unknown := strconv.Itoa(store.MockUnknownUserID)
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   string
+			code   int
+		}{
+			{"ban", http.MethodPut, "/v1/admin/users/2/ban", `{"reason": "spam"}`, http.StatusNoContent},
+			{"unban", http.MethodDelete, "/v1/admin/users/2/ban", "", http.StatusNoContent},
+			{"deactivate", http.MethodPut, "/v1/admin/users/2/deactivate", "", http.StatusNoContent},
+			{"reactivate", http.MethodDelete, "/v1/admin/users/2/deactivate", "", http.StatusNoContent},
+			{"force logout", http.MethodPost, "/v1/admin/users/2/logout", "", http.StatusNoContent},
+			{"force logout of themselves", http.MethodPost, "/v1/admin/users/" + self + "/logout", "", http.StatusNoContent},
+			{"ban an unknown user", http.MethodPut, "/v1/admin/users/" + unknown + "/ban", `{"reason": "spam"}`, http.StatusNotFound},
+			{"unban an unknown user", http.MethodDelete, "/v1/admin/users/" + unknown + "/ban", "", http.StatusNotFound},
+			{"deactivate an unknown user", http.MethodPut, "/v1/admin/users/" + unknown + "/deactivate", "", http.StatusNotFound},
+			{"force logout of an unknown user", http.MethodPost, "/v1/admin/users/" + unknown + "/logout", "", http.StatusNotFound},
+			{"ban themselves", http.MethodPut, "/v1/admin/users/" + self + "/ban", `{"reason": "spam"}`, http.StatusBadRequest},
+			{"deactivate themselves", http.MethodPut, "/v1/admin/users/" + self + "/deactivate", "", http.StatusBadRequest},
+			{"demote themselves", http.MethodPut, "/v1/admin/users/" + self + "/role", `{"role": "user"}`, http.StatusBadRequest},
+			{"ban user 0", http.MethodPut, "/v1/admin/users/0/ban", `{"reason": "spam"}`, http.StatusBadRequest},
+			{"force logout of user 0", http.MethodPost, "/v1/admin/users/0/logout", "", http.StatusBadRequest},
+			{"force logout of an invalid user", http.MethodPost, "/v1/admin/users/abc/logout", "", http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+
+				checkResponseCode(t, tt.code, executeRequest(req, mux).Code)
+			})
+		}
+	})
+
+	t.Run("should record the admin who moderated a user", func(t *testing.T) {
+		mux := app.mount()
+
+		audit := &recordingAuditStore{}
+		app.store.Audit = audit
+
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": int64(store.MockAdminUserID),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/admin/users/2/ban", strings.NewReader(`{"reason": "spam"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		checkResponseCode(t, http.StatusNoContent, executeRequest(req, mux).Code)
+
+		if len(audit.entries) != 1 {
+			t.Fatalf("Expected 1 audit entry. Got %d", len(audit.entries))
+		}
+
+		entry := audit.entries[0]
+		if entry.UserID == nil || *entry.UserID != 2 {
+			t.Errorf("Expected the banned user 2. Got %v", entry.UserID)
+		}
+		if entry.ActorID == nil || *entry.ActorID != store.MockAdminUserID {
+			t.Errorf("Expected the admin %d as actor. Got %v", store.MockAdminUserID, entry.ActorID)
+		}
+	})
+
+	t.Run("should reject banned users", func(t *testing.T) {
+		mux := app.mount()
+
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": int64(store.MockBannedUserID),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		checkResponseCode(t, http.StatusForbidden, executeRequest(req, mux).Code)
+	})
+}
+
+// recordingAuditStore keeps the audit entries in memory.
+type recordingAuditStore struct {
+	entries []*store.AuditEntry
+}
+
+func (s *recordingAuditStore) Log(ctx context.Context, entry *store.AuditEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
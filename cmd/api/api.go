@@ -6,11 +6,13 @@ import (
 	"expvar"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"github.com/Iowel/test-apps/internal/auth"
 	"github.com/Iowel/test-apps/internal/env"
 	"github.com/Iowel/test-apps/internal/mailer"
+	"github.com/Iowel/test-apps/internal/oidc"
 	"github.com/Iowel/test-apps/internal/ratelimiter"
 	"github.com/Iowel/test-apps/internal/store"
 	"github.com/Iowel/test-apps/internal/store/cache"
@@ -33,7 +35,9 @@ type application struct {
 	logger        *zap.SugaredLogger
 	mailer        mailer.Client
 	authenticator auth.Authenticator
-	rateLimiter   ratelimiter.Limiter
+	rateLimits    *rateLimits
+	// oidc is nil unless single sign-on is configured
+	oidc *oidc.Provider
 }
 
 type config struct {
@@ -46,6 +50,36 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	comments    commentsConfig
+	pagination  paginationConfig
+	invitations invitationsConfig
+	oidc        oidcConfig
+	// sweepInterval is how often expired invitations and tokens are purged
+	sweepInterval time.Duration
+	// trustedProxies may tell the client address with X-Forwarded-For and
+	// X-Real-IP
+	trustedProxies []netip.Prefix
+}
+
+type oidcConfig struct {
+	// issuer enables login with the OpenID provider it identifies
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+}
+
+type invitationsConfig struct {
+	// grace is how long a user is kept after their invitation expired
+	grace time.Duration
+}
+
+type paginationConfig struct {
+	cursorSecret string
+}
+
+type commentsConfig struct {
+	maxDepth int
 }
 
 type redisConfig struct {
@@ -58,12 +92,38 @@ type redisConfig struct {
 type authConfig struct {
 	basic basicConfig
 	token tokenConfig
+	login loginConfig
+	mfa   mfaConfig
+}
+
+type mfaConfig struct {
+	// encryptionKey encrypts the TOTP secrets stored in the database
+	encryptionKey string
+	// pendingExp is how long the code of a two-step login may be entered
+	pendingExp    time.Duration
+	recoveryCodes int
+}
+
+type loginConfig struct {
+	maxAttempts int
+	lockout     time.Duration
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
+	secret     string
+	exp        time.Duration
+	refreshExp time.Duration
+	iss        string
+	// signingKey switches from HS256 to RS256/EdDSA when set
+	signingKey keyConfig
+	// verificationKeys are previous signing keys kept during rotation,
+	// as "kid=path/to/public.pem" pairs
+	verificationKeys []string
+}
+
+type keyConfig struct {
+	id   string
+	path string
 }
 
 type basicConfig struct {
@@ -75,6 +135,7 @@ type mailConfig struct {
 	sendGrid  sendGridConfig
 	mailTrap  mailTrapConfig
 	exp       time.Duration
+	resetExp  time.Duration
 	fromEmail string
 }
 
@@ -98,7 +159,7 @@ func (app *application) mount() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.realIPMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
@@ -107,7 +168,7 @@ func (app *application) mount() http.Handler {
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -115,6 +176,8 @@ func (app *application) mount() http.Handler {
 
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	r.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	r.Route("/v1", func(r chi.Router) {
 		r.Get("/health", app.healthChechHandler)
 		r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
@@ -131,8 +194,26 @@ func (app *application) mount() http.Handler {
 				r.Use(app.postsContextMiddleware)
 
 				r.Get("/", app.getPostHandler)
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+				r.Patch("/", app.checkPostOwnership(store.PermUpdateAnyPost, app.updatePostHandler))
+				r.Delete("/", app.checkPostOwnership(store.PermDeleteAnyPost, app.deletePostHandler))
+
+				r.Put("/bookmark", app.bookmarkPostHandler)
+				r.Delete("/bookmark", app.unbookmarkPostHandler)
+
+				r.Put("/reactions/{kind}", app.putReactionHandler)
+				r.Delete("/reactions/{kind}", app.deleteReactionHandler)
+
+				r.Route("/comments", func(r chi.Router) {
+					r.Get("/", app.getPostCommentsHandler)
+					r.Post("/", app.createCommentHandler)
+
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.Use(app.commentsContextMiddleware)
+
+						r.Patch("/", app.checkCommentOwnership(store.PermUpdateAnyComment, app.updateCommentHandler))
+						r.Delete("/", app.checkCommentOwnership(store.PermDeleteAnyComment, app.deleteCommentHandler))
+					})
+				})
 			})
 		})
   
@@ -140,20 +221,62 @@ func (app *application) mount() http.Handler {
 		
 		r.Route("/users", func(r chi.Router) {
 			r.Get("/activate/{token}", app.activateUserHandler)
+			r.Post("/activate/resend", app.resendActivationHandler)
 
 			r.Route("/{userID}", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
 				r.Get("/", app.getUserHandler)
 
+				r.Get("/followers", app.getFollowersHandler)
+				r.Get("/following", app.getFollowingHandler)
+
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unFollowUserHandler)
+
+				r.Put("/block", app.blockUserHandler)
+				r.Delete("/block", app.unblockUserHandler)
+				r.Put("/mute", app.muteUserHandler)
+				r.Delete("/mute", app.unmuteUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Patch("/me", app.updateCurrentUserHandler)
+				r.Get("/me/bookmarks", app.getBookmarksHandler)
+				r.Get("/me/follow-requests", app.getFollowRequestsHandler)
+				r.Put("/me/follow-requests/{requesterID}/approve", app.approveFollowRequestHandler)
+				r.Put("/me/follow-requests/{requesterID}/reject", app.rejectFollowRequestHandler)
+				r.Get("/me/api-keys", app.getAPIKeysHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.requireSessionMiddleware)
+
+					r.Post("/me/api-keys", app.createAPIKeyHandler)
+					r.Delete("/me/api-keys/{keyID}", app.deleteAPIKeyHandler)
+
+					r.Post("/me/mfa", app.enrollMFAHandler)
+					r.Post("/me/mfa/verify", app.verifyMFAHandler)
+					r.Delete("/me/mfa", app.disableMFAHandler)
+				})
+			})
+		})
+
+		r.Route("/admin/users", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireSessionMiddleware)
+
+			r.With(app.requirePermission(store.PermReadUsers)).Get("/", app.listUsersHandler)
+
+			r.Route("/{userID}", func(r chi.Router) {
+				r.With(app.requirePermission(store.PermUpdateUserRole)).Put("/role", app.updateUserRoleHandler)
+				r.With(app.requirePermission(store.PermBanUsers)).Put("/ban", app.banUserHandler)
+				r.With(app.requirePermission(store.PermBanUsers)).Delete("/ban", app.unbanUserHandler)
+				r.With(app.requirePermission(store.PermBanUsers)).Put("/deactivate", app.deactivateUserHandler)
+				r.With(app.requirePermission(store.PermBanUsers)).Delete("/deactivate", app.reactivateUserHandler)
+				r.With(app.requirePermission(store.PermLogoutUsers)).Post("/logout", app.forceLogoutHandler)
 			})
 		})
 
@@ -161,6 +284,13 @@ func (app *application) mount() http.Handler {
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/mfa", app.mfaLoginHandler)
+			r.Get("/oidc/login", app.oidcLoginHandler)
+			r.Get("/oidc/callback", app.oidcCallbackHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.With(app.AuthTokenMiddleware).Post("/logout", app.logoutHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Post("/password/reset", app.resetPasswordHandler)
 		})
 	})
 
@@ -181,6 +311,11 @@ func (app *application) run(mux http.Handler) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go app.sweepExpired(ctx)
+
 	shutdown := make(chan error)
 
 	go func() {
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	apiKeyPrefix = "gsk_"
+
+	// apiKeyScopeRead allows safe (GET/HEAD/OPTIONS) requests only,
+	// apiKeyScopeWrite everything else as well.
+	apiKeyScopeRead  = "read"
+	apiKeyScopeWrite = "write"
+)
+
+var errAPIKeyScope = errors.New("api key is missing the required scope")
+
+type apiKeyKey string
+
+const apiKeyCtx apiKeyKey = "apiKey"
+
+// getAPIKeyFromContext returns the key the request was authenticated with,
+// nil for a bearer token.
+func getAPIKeyFromContext(r *http.Request) *store.APIKey {
+	key, _ := r.Context().Value(apiKeyCtx).(*store.APIKey)
+	return key
+}
+
+type CreateAPIKeyPayload struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read write"`
+	// ExpiresIn is the lifetime in days, keys don't expire without it
+	ExpiresIn int `json:"expires_in" validate:"omitempty,min=1,max=365"`
+}
+
+type APIKeyWithSecret struct {
+	*store.APIKey
+	Key string `json:"key"`
+}
+
+// createAPIKeyHandler creates a personal API key. The key itself is only
+// returned here, it can't be retrieved later.
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAPIKeyPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	plainKey, err := generateAPIKey()
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	key := &store.APIKey{
+		UserID: user.ID,
+		Name:   payload.Name,
+		Prefix: plainKey[:len(apiKeyPrefix)+8],
+		Scopes: payload.Scopes,
+	}
+
+	if payload.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(payload.ExpiresIn) * 24 * time.Hour)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := app.store.APIKeys.Create(r.Context(), key, plainKey); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, APIKeyWithSecret{key, plainKey}); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) getAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	keys, err := app.store.APIKeys.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, keys); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.APIKeys.Delete(r.Context(), user.ID, keyID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSessionMiddleware keeps API keys away from the credentials of their
+// owner: a leaked key must not be able to mint more keys or turn off 2FA.
+func (app *application) requireSessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getAPIKeyFromContext(r) != nil {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticateAPIKey resolves an "Authorization: ApiKey ..." header to the
+// owner of the key and checks the key may be used for the request.
+func (app *application) authenticateAPIKey(ctx context.Context, r *http.Request, plainKey string) (context.Context, error) {
+	key, err := app.store.APIKeys.GetByKey(ctx, plainKey)
+	if err != nil {
+		if err == store.ErrNotFound {
+			app.verifyAPIKey(plainKey, false)
+		}
+		return nil, err
+	}
+	app.verifyAPIKey(plainKey, true)
+
+	if !apiKeyAllows(key, r.Method) {
+		return nil, errAPIKeyScope
+	}
+
+	user, err := app.getUser(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsBanned() {
+		return nil, errUserBanned
+	}
+
+	if err := app.store.APIKeys.Touch(ctx, key.ID); err != nil {
+		app.logger.Errorw("failed to record api key use", "key", key.ID, "error", err)
+	}
+
+	ctx = context.WithValue(ctx, userCtx, user)
+	ctx = context.WithValue(ctx, apiKeyCtx, key)
+
+	return ctx, nil
+}
+
+func apiKeyAllows(key *store.APIKey, method string) bool {
+	if key.HasScope(apiKeyScopeWrite) {
+		return true
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return key.HasScope(apiKeyScopeRead)
+	default:
+		return false
+	}
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"github.com/Iowel/test-apps/internal/mailer"
@@ -114,6 +119,31 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+var (
+	errInvalidCredentials = errors.New("invalid credentials")
+	errUserBanned         = errors.New("user is banned")
+)
+
+// audit records an authentication or moderation event, along with the
+// signed in user who caused it. Failing to write the audit log must not fail
+// the request, so errors are only logged.
+func (app *application) audit(r *http.Request, userID *int64, email, event string) {
+	entry := &store.AuditEntry{
+		UserID: userID,
+		Email:  email,
+		Event:  event,
+		IP:     r.RemoteAddr,
+	}
+
+	if actor := getUserFromContext(r); actor != nil {
+		entry.ActorID = &actor.ID
+	}
+
+	if err := app.store.Audit.Log(r.Context(), entry); err != nil {
+		app.logger.Errorw("failed to write audit log", "event", event, "error", err)
+	}
+}
+
 type CreateUserTokenPayload struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
@@ -133,12 +163,19 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	ctx := r.Context()
+
 	// fetch the user (check if the user ixist) from payload
-	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
-			app.unauthorizedErrorResponse(w, r, err)
+			// burn the same time as a real password check
+			var nobody store.User
+			nobody.Password.Compare(payload.Password)
+
+			app.audit(r, nil, payload.Email, store.AuditLoginFailed)
+			app.unauthorizedErrorResponse(w, r, errInvalidCredentials)
 			return
 		default:
 			app.statusInternalServerError(w, r, err)
@@ -146,9 +183,123 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// generate token
+	// the password is checked even on locked accounts, so that a lockout
+	// can't be told apart from a wrong password by the response time
+	matches := user.Password.Compare(payload.Password)
+
+	if user.IsLocked() {
+		app.audit(r, &user.ID, payload.Email, store.AuditLoginLocked)
+		app.unauthorizedErrorResponse(w, r, errInvalidCredentials)
+		return
+	}
+
+	if !matches {
+		locked, err := app.store.Users.RegisterFailedLogin(ctx, user.ID, app.config.auth.login.maxAttempts, app.config.auth.login.lockout)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		app.audit(r, &user.ID, payload.Email, store.AuditLoginFailed)
+		if locked {
+			app.audit(r, &user.ID, payload.Email, store.AuditAccountLocked)
+		}
+
+		app.unauthorizedErrorResponse(w, r, errInvalidCredentials)
+		return
+	}
+
+	if user.IsBanned() {
+		app.audit(r, &user.ID, payload.Email, store.AuditLoginBanned)
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	// the failed logins are only reset once the second factor checked out,
+	// otherwise the password would reset the count of wrong codes
+	if user.TOTPEnabled {
+		mfaToken, err := app.generateMFAToken(user.ID)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		app.audit(r, &user.ID, payload.Email, store.AuditMFAPending)
+
+		pending := MFAPending{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(app.config.auth.mfa.pendingExp.Seconds()),
+		}
+
+		if err := app.jsonResponse(w, http.StatusAccepted, pending); err != nil {
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := app.store.Users.ResetFailedLogins(ctx, user.ID); err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.audit(r, &user.ID, payload.Email, store.AuditLoginSucceeded)
+
+	// generate tokens
+	tokens, err := app.issueTokens(ctx, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	// send it to client
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// issueTokens creates a short-lived access token and a refresh token that can
+// be exchanged for a new pair once the access token has expired.
+func (app *application) issueTokens(ctx context.Context, userID int64) (*TokenPair, error) {
+	accessToken, err := app.generateAccessToken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.store.Tokens.CreateRefreshToken(ctx, userID, refreshToken, app.config.auth.token.refreshExp); err != nil {
+		return nil, err
+	}
+
+	return app.newTokenPair(accessToken, refreshToken), nil
+}
+
+func (app *application) newTokenPair(accessToken, refreshToken string) *TokenPair {
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(app.config.auth.token.exp.Seconds()),
+	}
+}
+
+func (app *application) generateAccessToken(userID int64) (string, error) {
 	claims := jwt.MapClaims{
-		"sub": user.ID,
+		"sub": userID,
+		"jti": uuid.New().String(),
 		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
@@ -156,14 +307,220 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		"aud": app.config.auth.token.iss,
 	}
 
-	token, err := app.authenticator.GenerateToken(claims)
+	return app.authenticator.GenerateToken(claims)
+}
+
+// tokenAudience is the audience of the tokens only used for purpose. Other
+// services verifying our tokens with the published keys expect the access
+// token audience, so these can't pass as access tokens there.
+func (app *application) tokenAudience(purpose string) string {
+	return app.config.auth.token.iss + "/" + purpose
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+}
+
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	next, err := generateRefreshToken()
 	if err != nil {
 		app.statusInternalServerError(w, r, err)
 		return
 	}
 
-	// send it to client
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	userID, err := app.store.Tokens.RotateRefreshToken(ctx, payload.RefreshToken, next, app.config.auth.token.refreshExp)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		case store.ErrTokenReused:
+			app.logger.Warnw("refresh token reused, all sessions of its user revoked")
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, err := app.generateAccessToken(userID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, app.newTokenPair(accessToken, next)); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+type LogoutPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"max=255"`
+	// All signs the user out of every session, not just this one.
+	All bool `json:"all"`
+}
+
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var payload LogoutPayload
+
+	// the body is optional
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	if err := app.revokeAccessToken(ctx, getClaimsFromContext(r)); err != nil {
 		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	var err error
+	switch {
+	case payload.All:
+		err = app.store.Tokens.RevokeAllRefreshTokens(ctx, user.ID)
+	case payload.RefreshToken != "":
+		err = app.store.Tokens.RevokeRefreshToken(ctx, user.ID, payload.RefreshToken)
+	}
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// forgotPasswordHandler emails a one-time reset link. The response is the
+// same whether the email is known or not, so it can't be used to find out
+// which addresses have an account.
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	switch err {
+	case nil:
+	case store.ErrNotFound:
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(plainToken))
+	hashToken := hex.EncodeToString(hash[:])
+
+	if err := app.store.Users.CreatePasswordReset(ctx, user.ID, hashToken, app.config.mail.resetExp); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password?token=%s", app.config.frontendURL, plainToken),
+		ExpiresIn: app.config.mail.resetExp.String(),
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	// sending takes a while and its outcome must not be observable
+	go func() {
+		status, err := app.mailer.Send(mailer.PasswordResetTemplate, user.Username, user.Email, vars, !isProdEnv)
+		if err != nil {
+			app.logger.Errorw("error sending password reset email", "error", err)
+			return
+		}
+
+		app.logger.Infow("Email sent", "status code", status)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	userID, err := app.store.Users.ResetPassword(ctx, payload.Token, payload.Password)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the cached user must not keep its sessions alive
+	app.invalidateUser(ctx, userID)
+
+	w.WriteHeader(http.StatusNoContent)
 }
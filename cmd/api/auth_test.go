@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+	"github.com/Iowel/test-apps/internal/store/cache"
+)
+
+func TestCreateToken(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	loginAs := func(email, password string) int {
+		body := `{"email": "` + email + `", "password": "` + password + `"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	login := func(password string) int {
+		return loginAs("gopher@example.com", password)
+	}
+
+	t.Run("should issue tokens for the right password", func(t *testing.T) {
+		checkResponseCode(t, http.StatusCreated, login(store.MockPassword))
+	})
+
+	t.Run("should not issue tokens for a wrong password", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnauthorized, login("not-the-password"))
+	})
+
+	t.Run("should not issue tokens for locked accounts", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnauthorized, loginAs(store.MockLockedEmail, store.MockPassword))
+	})
+}
+
+func TestMFAPendingToken(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.auth.mfa.pendingExp = time.Minute
+	app.config.auth.token.iss = "test-aud"
+	mux := app.mount()
+
+	token, err := app.generateMFAToken(25)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not be accepted as an access token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	exchange := func(token string) int {
+		body := `{"mfa_token": "` + token + `", "code": "123456"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/mfa", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	mfa := app.store.MFA.(*store.MockMFAStore)
+
+	t.Run("should not be exchanged without two-factor enrollment", func(t *testing.T) {
+		mfa.On("Get", int64(25)).Return(nil, store.ErrNotFound).Once()
+
+		checkResponseCode(t, http.StatusUnauthorized, exchange(token))
+		mfa.AssertExpectations(t)
+	})
+
+	t.Run("should not be exchanged after the sessions were revoked", func(t *testing.T) {
+		token, err := app.generateMFAToken(store.MockSignedOutUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checkResponseCode(t, http.StatusUnauthorized, exchange(token))
+		mfa.AssertNotCalled(t, "Get", int64(store.MockSignedOutUserID))
+	})
+
+	t.Run("should not be accepted for another audience", func(t *testing.T) {
+		if _, err := app.authenticator.ValidateTokenFor(token, app.config.auth.token.iss); err == nil {
+			t.Error("Expected a pending token to be rejected for the access token audience")
+		}
+	})
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	request := func(method, path, key string) int {
+		req, err := http.NewRequest(method, path, strings.NewReader(`{"name": "bot", "scopes": ["read"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+key)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should allow reading with a read key", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, request(http.MethodGet, "/v1/users/me/api-keys", store.MockAPIKey))
+	})
+
+	t.Run("should reject unknown keys", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnauthorized, request(http.MethodGet, "/v1/users/me/api-keys", "gsk_unknown"))
+	})
+
+	t.Run("should not allow writing with a read key", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, request(http.MethodPut, "/v1/users/1/follow", store.MockAPIKey))
+	})
+
+	t.Run("should not allow creating keys with a key", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, request(http.MethodPost, "/v1/users/me/api-keys", store.MockWriteAPIKey))
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.redisCfg.enabled = true
+	mux := app.mount()
+
+	// a token is consumed by its first use, expired tokens are never found
+	userStore := app.store.Users.(*store.MockUserStore)
+	userStore.On("ResetPassword", "valid-token").Return(int64(7), nil).Once()
+	userStore.On("ResetPassword", "valid-token").Return(int64(0), store.ErrNotFound)
+	userStore.On("ResetPassword", "expired-token").Return(int64(0), store.ErrNotFound)
+
+	cacheUsers := app.cacheStorage.Users.(*cache.MockUserStore)
+	cacheUsers.On("Delete", int64(7)).Return(nil)
+
+	reset := func(token, password string) int {
+		body := `{"token": "` + token + `", "password": "` + password + `"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/reset", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should reset the password once per token", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, reset("valid-token", "new-password"))
+		checkResponseCode(t, http.StatusNotFound, reset("valid-token", "new-password"))
+
+		// the cached user picks up the revoked sessions
+		cacheUsers.AssertCalled(t, "Delete", int64(7))
+	})
+
+	t.Run("should not reset the password with an expired token", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, reset("expired-token", "new-password"))
+	})
+
+	t.Run("should reject invalid passwords", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, reset("valid-token", ""))
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Iowel/test-apps/internal/store"
+)
+
+var errSelfRelation = errors.New("you cannot do that to yourself")
+
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.relationHandler(w, r, app.store.Blocks.Block)
+}
+
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.relationHandler(w, r, app.store.Blocks.Unblock)
+}
+
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.relationHandler(w, r, app.store.Blocks.Mute)
+}
+
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.relationHandler(w, r, app.store.Blocks.Unmute)
+}
+
+// relationHandler applies fn between the authenticated user and the user in
+// the URL.
+func (app *application) relationHandler(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, userID, otherID int64) error) {
+	user := getUserFromContext(r)
+
+	otherID, err := readUserIDParam(r, "userID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if otherID == user.ID {
+		app.badRequestResponse(w, r, errSelfRelation)
+		return
+	}
+
+	if err := fn(r.Context(), user.ID, otherID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
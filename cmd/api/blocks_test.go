@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRelations(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": int64(7),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relate := func(t *testing.T, method, path string) int {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, relation := range []string{"block", "mute"} {
+		t.Run("should "+relation+" other users", func(t *testing.T) {
+			checkResponseCode(t, http.StatusNoContent, relate(t, http.MethodPut, "/v1/users/6/"+relation))
+			checkResponseCode(t, http.StatusNoContent, relate(t, http.MethodDelete, "/v1/users/6/"+relation))
+		})
+
+		t.Run("should not "+relation+" unknown users", func(t *testing.T) {
+			path := fmt.Sprintf("/v1/users/%d/%s", store.MockUnknownUserID, relation)
+			checkResponseCode(t, http.StatusNotFound, relate(t, http.MethodPut, path))
+		})
+
+		t.Run("should reject invalid users to "+relation, func(t *testing.T) {
+			checkResponseCode(t, http.StatusBadRequest, relate(t, http.MethodPut, "/v1/users/0/"+relation))
+			checkResponseCode(t, http.StatusBadRequest, relate(t, http.MethodPut, "/v1/users/7/"+relation))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+)
+
+func (app *application) bookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.Add(r.Context(), user.ID, post.ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) unbookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.Remove(r.Context(), user.ID, post.ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	scope := cursorScope("bookmarks", user.ID, "")
+
+	q, err := app.readPaginatedQuery(r, scope)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bookmarks, next, err := app.store.Bookmarks.GetByUserID(r.Context(), user.ID, q)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, bookmarks, next, scope); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestBookmarks(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	bookmarkStore := app.store.Bookmarks.(*store.MockBookmarkStore)
+	bookmarkStore.On("Add", int64(7), int64(1)).Return(nil)
+	bookmarkStore.On("Remove", int64(7), int64(1)).Return(nil)
+	bookmarkStore.On("GetByUserID", int64(7), (*store.Cursor)(nil)).Return([]store.BookmarkedPost{
+		{Post: store.Post{ID: 1}},
+	}, nil, nil)
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": int64(7),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, path string) *http.Response {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should accept bookmarking a post twice", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, request(t, http.MethodPut, "/v1/posts/1/bookmark").StatusCode)
+		checkResponseCode(t, http.StatusNoContent, request(t, http.MethodPut, "/v1/posts/1/bookmark").StatusCode)
+
+		bookmarkStore.AssertNumberOfCalls(t, "Add", 2)
+	})
+
+	t.Run("should remove bookmarks", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, request(t, http.MethodDelete, "/v1/posts/1/bookmark").StatusCode)
+
+		bookmarkStore.AssertCalled(t, "Remove", int64(7), int64(1))
+	})
+
+	t.Run("should list the bookmarks of the user", func(t *testing.T) {
+		res := request(t, http.MethodGet, "/v1/users/me/bookmarks")
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		var body struct {
+			Data []store.BookmarkedPost `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 1 || body.Data[0].ID != 1 {
+			t.Errorf("Expected bookmarked post 1. Got %+v", body.Data)
+		}
+	})
+
+	t.Run("should reject invalid cursors", func(t *testing.T) {
+		res := request(t, http.MethodGet, "/v1/users/me/bookmarks?cursor=garbage")
+		checkResponseCode(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGetPostComments(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	parentID := int64(2)
+	reply := &store.Comment{ID: 3, PostID: 1, ParentID: &parentID, Depth: 1}
+	next := &store.Cursor{CreatedAt: "2024-01-01 00:00:00", ID: 2}
+
+	commentStore := app.store.Comments.(*store.MockCommentStore)
+	commentStore.On("GetByPostID", int64(1), (*store.Cursor)(nil)).Return([]store.Comment{
+		{ID: 2, PostID: 1, Replies: []*store.Comment{reply}},
+	}, next, nil)
+	commentStore.On("GetByPostID", int64(1), next).Return([]store.Comment{
+		{ID: 1, PostID: 1},
+	}, nil, nil)
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": int64(1),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getComments := func(t *testing.T, cursor string) (int, []store.Comment, string) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/posts/1/comments?cursor="+url.QueryEscape(cursor), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		var body struct {
+			Data       []store.Comment `json:"data"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return rr.Code, body.Data, body.NextCursor
+	}
+
+	t.Run("should page through the threads of a post", func(t *testing.T) {
+		code, comments, cursor := getComments(t, "")
+		checkResponseCode(t, http.StatusOK, code)
+
+		if len(comments) != 1 || comments[0].ID != 2 || len(comments[0].Replies) != 1 || comments[0].Replies[0].ID != 3 {
+			t.Fatalf("Expected thread 2 with reply 3. Got %+v", comments)
+		}
+		if cursor == "" {
+			t.Fatal("Expected a cursor to the next page")
+		}
+
+		code, comments, cursor = getComments(t, cursor)
+		checkResponseCode(t, http.StatusOK, code)
+
+		if len(comments) != 1 || comments[0].ID != 1 {
+			t.Errorf("Expected thread 1 on the last page. Got %+v", comments)
+		}
+		if cursor != "" {
+			t.Errorf("Expected no cursor after the last page. Got %q", cursor)
+		}
+	})
+
+	t.Run("should reject forged cursors, cursors of other listings and offsets", func(t *testing.T) {
+		forged := next.Encode([]byte("not-the-secret"), cursorScope("comments", 1, ""))
+		otherPost := app.encodeCursor(next, cursorScope("comments", 2, ""))
+		followers := app.encodeCursor(next, cursorScope("followers", 1, ""))
+		offset := app.encodeCursor(&store.Cursor{Offset: 20}, cursorScope("comments", 1, ""))
+
+		for _, cursor := range []string{"garbage", forged, otherPost, followers, offset} {
+			code, _, _ := getComments(t, cursor)
+			checkResponseCode(t, http.StatusBadRequest, code)
+		}
+	})
+}
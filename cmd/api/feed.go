@@ -25,14 +25,41 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	ctx := r.Context()
+	user := getUserFromContext(r)
 
-	feed, err := app.store.Posts.GetUserFeed(ctx, int64(1), fq)
+	scope := cursorScope("feed", user.ID, fq.Sort)
+
+	// ranked feeds page by offset, the others by key
+	if fq.IsRanked() {
+		fq.After, err = app.decodeCursor(fq.Cursor, scope)
+		if err == nil && fq.After != nil && fq.After.IsKeyset() {
+			err = store.ErrInvalidCursor
+		}
+	} else {
+		fq.After, err = app.decodeKeysetCursor(fq.Cursor, scope)
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	feed, next, err := app.store.Posts.GetUserFeed(ctx, user.ID, fq)
 	if err != nil {
 		app.statusInternalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
+	posts := make([]*store.Post, 0, len(feed))
+	for i := range feed {
+		posts = append(posts, &feed[i].Post)
+	}
+
+	if err := app.attachReactions(ctx, posts, user.ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, feed, next, scope); err != nil {
 		app.statusInternalServerError(w, r, err)
 	}
 }
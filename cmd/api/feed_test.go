@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	postStore := app.store.Posts.(*store.MockPostStore)
+	postStore.On("GetUserFeed", int64(1)).Return([]store.PostWithMetadata{
+		{Post: store.Post{ID: 10, UserID: 1}},
+		{Post: store.Post{ID: 11, UserID: 3}},
+	}, nil, nil)
+	postStore.On("GetUserFeed", int64(2)).Return([]store.PostWithMetadata{
+		{Post: store.Post{ID: 20, UserID: 2}},
+	}, nil, nil)
+
+	tokenFor := func(userID int64) string {
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": userID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	getFeed := func(t *testing.T, token string) []int64 {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []store.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]int64, 0, len(body.Data))
+		for _, p := range body.Data {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should serve each user their own feed", func(t *testing.T) {
+		first := getFeed(t, tokenFor(1))
+		second := getFeed(t, tokenFor(2))
+
+		if len(first) != 2 || first[0] != 10 || first[1] != 11 {
+			t.Errorf("Expected feed [10 11] for user 1. Got %v", first)
+		}
+
+		if len(second) != 1 || second[0] != 20 {
+			t.Errorf("Expected feed [20] for user 2. Got %v", second)
+		}
+
+		postStore.AssertCalled(t, "GetUserFeed", int64(1))
+		postStore.AssertCalled(t, "GetUserFeed", int64(2))
+	})
+
+	t.Run("should only accept the cursors of the feed's own paging", func(t *testing.T) {
+		keyset := &store.Cursor{CreatedAt: "2024-01-01 00:00:00", ID: 10}
+		offset := &store.Cursor{Offset: 20, RankedAt: "2024-01-01T00:00:00Z"}
+
+		tests := []struct {
+			sort   string
+			cursor *store.Cursor
+			code   int
+		}{
+			{"desc", keyset, http.StatusOK},
+			{"desc", offset, http.StatusBadRequest},
+			{"hot", offset, http.StatusOK},
+			{"hot", keyset, http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			cursor := app.encodeCursor(tt.cursor, cursorScope("feed", 1, tt.sort))
+
+			req, err := http.NewRequest(http.MethodGet, "/v1/users/feed?sort="+tt.sort+"&cursor="+cursor, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+tokenFor(1))
+
+			rr := executeRequest(req, mux)
+
+			checkResponseCode(t, tt.code, rr.Code)
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/Iowel/test-apps/internal/auth"
 )
 
 func (app *application) healthChechHandler(w http.ResponseWriter, r *http.Request) {
@@ -15,3 +18,17 @@ func (app *application) healthChechHandler(w http.ResponseWriter, r *http.Reques
 		app.statusInternalServerError(w, r, err)
 	}
 }
+
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	publisher, ok := app.authenticator.(auth.KeySetPublisher)
+	if !ok {
+		app.notFoundResponse(w, r, errors.New("tokens are not signed with a public key"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := writeJSON(w, http.StatusOK, publisher.JWKS()); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/Iowel/test-apps/internal/store"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -46,3 +49,69 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 
 	return writeJSON(w, status, &envelope{Data: data})
 }
+
+func (app *application) paginatedJSONResponse(w http.ResponseWriter, status int, data any, next *store.Cursor, scope string) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor"`
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: app.encodeCursor(next, scope)})
+}
+
+// readPaginatedQuery parses and validates the paging parameters of a simple
+// listing endpoint.
+func (app *application) readPaginatedQuery(r *http.Request, scope string) (store.PaginatedQuery, error) {
+	q := store.PaginatedQuery{
+		Limit: 20,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		return q, err
+	}
+
+	if err := Validate.Struct(q); err != nil {
+		return q, err
+	}
+
+	q.After, err = app.decodeKeysetCursor(q.Cursor, scope)
+	return q, err
+}
+
+// cursorScope names the listing a cursor is issued for: the route, whose
+// items it lists and, where the order can be chosen, the sort order.
+func cursorScope(route string, ownerID int64, sort string) string {
+	return fmt.Sprintf("%s:%d:%s", route, ownerID, sort)
+}
+
+func (app *application) encodeCursor(c *store.Cursor, scope string) string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Encode([]byte(app.config.pagination.cursorSecret), scope)
+}
+
+func (app *application) decodeCursor(s, scope string) (*store.Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	return store.DecodeCursor(s, []byte(app.config.pagination.cursorSecret), scope)
+}
+
+// decodeKeysetCursor decodes the cursor of a listing paged by (created_at,
+// id) only, which never issues offset cursors.
+func (app *application) decodeKeysetCursor(s, scope string) (*store.Cursor, error) {
+	c, err := app.decodeCursor(s, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil && !c.IsKeyset() {
+		return nil, store.ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"expvar"
+	"fmt"
 	"log"
 	"runtime"
+	"strings"
 	"github.com/Iowel/test-apps/internal/auth"
 	"github.com/Iowel/test-apps/internal/db"
 	"github.com/Iowel/test-apps/internal/env"
 	"github.com/Iowel/test-apps/internal/mailer"
+	"github.com/Iowel/test-apps/internal/oidc"
 	"github.com/Iowel/test-apps/internal/ratelimiter"
 	"github.com/Iowel/test-apps/internal/store"
 	"github.com/Iowel/test-apps/internal/store/cache"
@@ -44,6 +47,7 @@ func main() {
 		frontendURL: env.GetString("FRONTEND_URL", "http://localhost:8080"),
 		mail: mailConfig{
 			exp:       time.Hour * 24 * 3,
+			resetExp:  time.Hour,
 			fromEmail: env.GetString("SENDGRID_FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", ""),
@@ -59,9 +63,24 @@ func main() {
 				pass: env.GetString("AUTH_BASIC_PASS", "admin"),
 			},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_TOKEN_SECRET", "example"),
-				exp:    time.Hour * 24 * 3, // 3 days
-				iss:    "gophersocial",
+				secret:     env.GetString("AUTH_TOKEN_SECRET", "example"),
+				exp:        time.Minute * 15,
+				refreshExp: time.Hour * 24 * 30, // 30 days
+				iss:        "gophersocial",
+				signingKey: keyConfig{
+					id:   env.GetString("AUTH_TOKEN_SIGNING_KEY_ID", ""),
+					path: env.GetString("AUTH_TOKEN_SIGNING_KEY_FILE", ""),
+				},
+				verificationKeys: env.GetList("AUTH_TOKEN_VERIFICATION_KEYS", nil),
+			},
+			login: loginConfig{
+				maxAttempts: env.GetInt("AUTH_LOGIN_MAX_ATTEMPTS", 5),
+				lockout:     time.Minute * 15,
+			},
+			mfa: mfaConfig{
+				encryptionKey: env.GetString("MFA_ENCRYPTION_KEY", "example"),
+				pendingExp:    time.Minute * 5,
+				recoveryCodes: 10,
 			},
 		},
 		db: dbConfig{
@@ -81,6 +100,26 @@ func main() {
 			RequestsPerTimeFrame: env.GetInt("RATELIMITER_REQUESTS_COUNT", 20),
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
+			Strategy:             env.GetString("RATE_LIMITER_STRATEGY", ratelimiter.FixedWindow),
+			Redis:                env.GetBool("RATE_LIMITER_REDIS", false),
+			FailOpen:             env.GetBool("RATE_LIMITER_FAIL_OPEN", true),
+			PoliciesFile:         env.GetString("RATE_LIMITER_POLICIES", ""),
+		},
+		oidc: oidcConfig{
+			issuer:       env.GetString("OIDC_ISSUER", ""),
+			clientID:     env.GetString("OIDC_CLIENT_ID", ""),
+			clientSecret: env.GetString("OIDC_CLIENT_SECRET", ""),
+			redirectURL:  env.GetString("OIDC_REDIRECT_URL", "http://localhost:8080/v1/authentication/oidc/callback"),
+		},
+		invitations: invitationsConfig{
+			grace: time.Hour * 24 * 7,
+		},
+		sweepInterval: time.Hour,
+		comments: commentsConfig{
+			maxDepth: env.GetInt("COMMENTS_MAX_DEPTH", 5),
+		},
+		pagination: paginationConfig{
+			cursorSecret: env.GetString("PAGINATION_CURSOR_SECRET", "example"),
 		},
 	}
 
@@ -88,6 +127,12 @@ func main() {
 	logger := zap.Must(zap.NewProduction()).Sugar()
 	defer logger.Sync()
 
+	trustedProxies, err := parseTrustedProxies(env.GetList("TRUSTED_PROXIES", nil))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	cfg.trustedProxies = trustedProxies
+
 	// Database
 	db, err := db.New(cfg.db.addr, cfg.db.maxOpenConns, cfg.db.maxIdleCons, cfg.db.maxIdleTime)
 	if err != nil {
@@ -108,11 +153,17 @@ func main() {
 
 	cacheStorage := cache.NewRedisStorage(redisDB)
 
+	if cfg.redisCfg.enabled {
+		dbStorage = cache.NewCachedStorage(dbStorage, redisDB, func(err error) {
+			logger.Errorw("failed to invalidate cache", "err", err)
+		})
+	}
+
 	// Rate limiter
-	rateLimiter := ratelimiter.NewFixedWindowLimiter(
-		cfg.rateLimiter.RequestsPerTimeFrame,
-		cfg.rateLimiter.TimeFrame,
-	)
+	rateLimits, err := newRateLimits(cfg.rateLimiter, redisDB, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	// mailSendGrid := mailer.NewSendgrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail)
 	mailTrap, err := mailer.NewMailtrapClient(cfg.mail.mailTrap.username, cfg.mail.mailTrap.password, cfg.mail.fromEmail)
@@ -120,7 +171,20 @@ func main() {
 		logger.Fatal(err)
 	}
 
-	jwtAuthenticator := auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
+	jwtAuthenticator, err := newAuthenticator(cfg.auth.token)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	var oidcProvider *oidc.Provider
+	if cfg.oidc.issuer != "" {
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.oidc.issuer,
+			ClientID:     cfg.oidc.clientID,
+			ClientSecret: cfg.oidc.clientSecret,
+			RedirectURL:  cfg.oidc.redirectURL,
+		}, nil)
+	}
 
 	app := &application{
 		config:        cfg,
@@ -129,7 +193,8 @@ func main() {
 		logger:        logger,
 		mailer:        mailTrap,
 		authenticator: jwtAuthenticator,
-		rateLimiter:   rateLimiter,
+		rateLimits:    rateLimits,
+		oidc:          oidcProvider,
 	}
 
 	// Metrics
@@ -145,3 +210,30 @@ func main() {
 
 	log.Fatal(app.run(mux))
 }
+
+func newAuthenticator(cfg tokenConfig) (auth.Authenticator, error) {
+	if cfg.signingKey.path == "" {
+		return auth.NewJWTAuthenticator(cfg.secret, cfg.iss, cfg.iss), nil
+	}
+
+	signing, err := auth.LoadPrivateKey(cfg.signingKey.id, cfg.signingKey.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var verification []*auth.Key
+	for _, pair := range cfg.verificationKeys {
+		id, path, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("verification key %q is not a kid=path pair", pair)
+		}
+
+		key, err := auth.LoadPublicKey(id, path)
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+
+	return auth.NewKeySetAuthenticator(signing, verification, cfg.iss, cfg.iss)
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/auth"
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mfaPendingTokenType marks the token handed out after the password of a
+// two-factor user was verified. It only grants exchanging a code for a real
+// token pair, AuthTokenMiddleware rejects it.
+const mfaPendingTokenType = "mfa_pending"
+
+// mfaAudience keeps pending tokens from passing as access tokens, see
+// tokenAudience.
+const mfaAudience = "mfa"
+
+var (
+	errInvalidMFACode   = errors.New("invalid two-factor code")
+	errMFAAlreadyActive = errors.New("two-factor authentication is already enabled")
+)
+
+type MFAEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAPending struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type MFACodePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type MFALoginPayload struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// enrollMFAHandler generates a TOTP secret and recovery codes. Two-factor
+// authentication is only enabled once a code has been verified, so a user
+// who never scans the QR code isn't locked out.
+func (app *application) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	totp, err := app.store.MFA.Get(ctx, user.ID)
+	switch {
+	case err == nil && totp.Enabled:
+		app.conflictResponse(w, r, errMFAAlreadyActive)
+		return
+	case err != nil && err != store.ErrNotFound:
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	sealed, err := auth.Seal(app.config.auth.mfa.encryptionKey, secret)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	codes, err := generateRecoveryCodes(app.config.auth.mfa.recoveryCodes)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.Enroll(ctx, user.ID, sealed, codes); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	enrollment := MFAEnrollment{
+		Secret:        auth.EncodeTOTPSecret(secret),
+		URI:           auth.TOTPURI(app.config.auth.token.iss, user.Email, secret),
+		RecoveryCodes: codes,
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, enrollment); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	totp, err := app.store.MFA.Get(ctx, user.ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if totp.Enabled {
+		app.conflictResponse(w, r, errMFAAlreadyActive)
+		return
+	}
+
+	// only a TOTP code proves the app was set up, not a recovery code
+	ok, err := app.checkTOTPCode(ctx, user.ID, totp, payload.Code)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		app.badRequestResponse(w, r, errInvalidMFACode)
+		return
+	}
+
+	if err := app.store.MFA.Enable(ctx, user.ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	app.audit(r, &user.ID, user.Email, store.AuditMFAEnabled)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	ok, err := app.checkMFACode(ctx, user.ID, payload.Code)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !ok {
+		app.badRequestResponse(w, r, errInvalidMFACode)
+		return
+	}
+
+	if err := app.store.MFA.Disable(ctx, user.ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	app.audit(r, &user.ID, user.Email, store.AuditMFADisabled)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mfaLoginHandler completes a two-step login: the pending token from
+// createTokenHandler and a TOTP or recovery code are exchanged for a token
+// pair. Wrong codes count as failed logins, so they lock the account too.
+func (app *application) mfaLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFALoginPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	jwtToken, err := app.authenticator.ValidateTokenFor(payload.MFAToken, app.tokenAudience(mfaAudience))
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != mfaPendingTokenType {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("not a two-factor token"))
+		return
+	}
+
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	revoked, err := app.isAccessTokenRevoked(ctx, claims)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if revoked {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+		return
+	}
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, errInvalidCredentials)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.IsLocked() {
+		app.audit(r, &user.ID, user.Email, store.AuditLoginLocked)
+		app.unauthorizedErrorResponse(w, r, errInvalidCredentials)
+		return
+	}
+
+	if user.IsBanned() {
+		app.audit(r, &user.ID, user.Email, store.AuditLoginBanned)
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	// a forced logout or a password reset since the password was checked
+	// ends the login too
+	if issuedBeforeRevocation(claims, user) {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+		return
+	}
+
+	ok, err := app.checkMFACode(ctx, user.ID, payload.Code)
+	if err != nil && err != store.ErrNotFound {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		locked, err := app.store.Users.RegisterFailedLogin(ctx, user.ID, app.config.auth.login.maxAttempts, app.config.auth.login.lockout)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		app.audit(r, &user.ID, user.Email, store.AuditMFAFailed)
+		if locked {
+			app.audit(r, &user.ID, user.Email, store.AuditAccountLocked)
+		}
+
+		app.unauthorizedErrorResponse(w, r, errInvalidMFACode)
+		return
+	}
+
+	// the pending token is single use
+	if err := app.revokeAccessToken(ctx, claims); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := app.store.Users.ResetFailedLogins(ctx, user.ID); err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.audit(r, &user.ID, user.Email, store.AuditLoginSucceeded)
+
+	tokens, err := app.issueTokens(ctx, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) generateMFAToken(userID int64) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"typ": mfaPendingTokenType,
+		"jti": uuid.New().String(),
+		"exp": time.Now().Add(app.config.auth.mfa.pendingExp).Unix(),
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.tokenAudience(mfaAudience),
+	}
+
+	return app.authenticator.GenerateToken(claims)
+}
+
+// checkMFACode accepts either a TOTP code or an unused recovery code of an
+// enabled enrollment.
+func (app *application) checkMFACode(ctx context.Context, userID int64, code string) (bool, error) {
+	totp, err := app.store.MFA.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if !totp.Enabled {
+		return false, store.ErrNotFound
+	}
+
+	if len(code) == auth.TOTPDigits {
+		return app.checkTOTPCode(ctx, userID, totp, code)
+	}
+
+	return app.store.MFA.UseRecoveryCode(ctx, userID, normalizeRecoveryCode(code))
+}
+
+func (app *application) checkTOTPCode(ctx context.Context, userID int64, totp *store.TOTP, code string) (bool, error) {
+	secret, err := auth.Open(app.config.auth.mfa.encryptionKey, totp.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// a code is only good once
+	return app.store.MFA.UseStep(ctx, userID, step)
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns n random codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 10 {
+		return code
+	}
+
+	return code[:5] + "-" + code[5:]
+}
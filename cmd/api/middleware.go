@@ -9,6 +9,7 @@ import (
 	"github.com/Iowel/test-apps/internal/store"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -22,11 +23,27 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
 			return
 		}
 
+		if parts[0] == "ApiKey" {
+			ctx, err := app.authenticateAPIKey(r.Context(), r, parts[1])
+			if err != nil {
+				switch err {
+				case errAPIKeyScope, errUserBanned:
+					app.forbiddenResponse(w, r)
+				default:
+					app.unauthorizedErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		token := parts[1]
 
 		jwtToken, err := app.authenticator.ValidateToken(token)
@@ -37,6 +54,13 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 
 		claims := jwtToken.Claims.(jwt.MapClaims)
 
+		// access tokens carry no type, anything else (e.g. a pending
+		// two-factor login) must not grant access
+		if typ, _ := claims["typ"].(string); typ != "" {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("not an access token"))
+			return
+		}
+
 		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
@@ -45,6 +69,17 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 
+		revoked, err := app.isAccessTokenRevoked(ctx, claims)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		if revoked {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
 		log.Println("userID", userID)
 
 		user, err := app.getUser(ctx, userID)
@@ -53,12 +88,113 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if user.IsBanned() {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		if issuedBeforeRevocation(claims, user) {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, claimsCtx, claims)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+type claimsKey string
+
+const claimsCtx claimsKey = "claims"
+
+func getClaimsFromContext(r *http.Request) jwt.MapClaims {
+	claims, _ := r.Context().Value(claimsCtx).(jwt.MapClaims)
+	return claims
+}
+
+// isAccessTokenRevoked checks the jti of a token against the denylist.
+// Tokens issued before jti was introduced can't be revoked and simply run
+// out.
+func (app *application) isAccessTokenRevoked(ctx context.Context, claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return false, nil
+	}
+
+	if !app.config.redisCfg.enabled {
+		return app.store.Tokens.IsAccessTokenRevoked(ctx, jti)
+	}
+
+	revoked, ok, err := app.cacheStorage.Tokens.Get(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		return revoked, nil
+	}
+
+	revoked, err = app.store.Tokens.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	if err := app.cacheStorage.Tokens.Set(ctx, jti, revoked, claimsExpiry(claims)); err != nil {
+		app.logger.Errorw("failed to set token cache", "jti", jti, "err", err)
+	}
+
+	return revoked, nil
+}
+
+// revokeAccessToken denylists the token the claims belong to until it expires.
+func (app *application) revokeAccessToken(ctx context.Context, claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil
+	}
+
+	expiry := claimsExpiry(claims)
+
+	if err := app.store.Tokens.RevokeAccessToken(ctx, jti, expiry); err != nil {
+		return err
+	}
+
+	if app.config.redisCfg.enabled {
+		if err := app.cacheStorage.Tokens.Set(ctx, jti, true, expiry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// issuedBeforeRevocation reports whether the token was issued before all
+// sessions of user were revoked. Tokens of the very same second are
+// rejected too, timestamps only have second precision.
+func issuedBeforeRevocation(claims jwt.MapClaims, user *store.User) bool {
+	if user.SessionsRevokedAt == nil {
+		return false
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return true
+	}
+
+	return !iat.Time.After(*user.SessionsRevokedAt)
+}
+
+func claimsExpiry(claims jwt.MapClaims) time.Time {
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Now()
+	}
+
+	return exp.Time
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -98,7 +234,25 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
-func (app *application) checkPostOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+// requirePermission only lets users whose role grants permission through.
+func (app *application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			if !user.Role.HasPermission(permission) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkPostOwnership lets the author of a post through, everybody else needs
+// permission (e.g. store.PermUpdateAnyPost).
+func (app *application) checkPostOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := getUserFromContext(r)
 		post := getPostFromCtx(r)
@@ -108,30 +262,22 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 			return
 		}
 
-		// check role
-		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
-		if err != nil {
-			app.statusInternalServerError(w, r, err)
-			return
-		}
-
-		if !allowed {
-			app.forbiddenResponse(w, r)
-			return
-		}
-
-		next.ServeHTTP(w, r)
+		app.requirePermission(permission)(next).ServeHTTP(w, r)
 	})
 }
 
-func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := app.store.Roles.GetByName(ctx, roleName)
-	if err != nil {
-		return false, err
-	}
+func (app *application) checkCommentOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		comment := getCommentFromCtx(r)
 
-	return user.Role.Level >= role.Level, nil
+		if comment.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		app.requirePermission(permission)(next).ServeHTTP(w, r)
+	})
 }
 
 func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
@@ -163,11 +309,25 @@ func (app *application) getUser(ctx context.Context, userID int64) (*store.User,
 	return user, nil
 }
 
+// invalidateUser drops the cached copy of a user after it changed.
+func (app *application) invalidateUser(ctx context.Context, userID int64) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Users.Delete(ctx, userID); err != nil {
+		app.logger.Errorw("failed to invalidate user cache", "id", userID, "err", err)
+	}
+}
+
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
-			if allow, retryAfter := app.rateLimiter.Allow(r.RemoteAddr); !allow {
-				app.rateLimitExceededResponse(w, r, retryAfter.String())
+			res := app.allowRequest(r)
+			setRateLimitHeaders(w, res)
+
+			if !res.Allowed {
+				app.rateLimitExceededResponse(w, r, seconds(res.RetryAfter))
 				return
 			}
 		}
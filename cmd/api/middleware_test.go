@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequirePermission(t *testing.T) {
+	app := newTestApplication(t)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := app.requirePermission(store.PermBanUsers)(ok)
+
+	serve := func(role store.Role) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		ctx := context.WithValue(req.Context(), userCtx, &store.User{ID: 1, Role: role})
+
+		return executeRequest(req.WithContext(ctx), handler).Code
+	}
+
+	t.Run("should allow roles granting the permission", func(t *testing.T) {
+		role := store.Role{Name: "admin", Permissions: []string{store.PermBanUsers, store.PermDeleteAnyPost}}
+		checkResponseCode(t, http.StatusNoContent, serve(role))
+	})
+
+	t.Run("should forbid roles without the permission", func(t *testing.T) {
+		role := store.Role{Name: "moderator", Permissions: []string{store.PermUpdateAnyPost}}
+		checkResponseCode(t, http.StatusForbidden, serve(role))
+	})
+
+	t.Run("should forbid deleting posts of others without the permission", func(t *testing.T) {
+		mux := app.mount()
+
+		token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+			"sub": int64(1),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		checkResponseCode(t, http.StatusForbidden, executeRequest(req, mux).Code)
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/oidc"
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcStateTokenType = "oidc_state"
+	oidcStateCookie    = "oidc_state"
+	oidcStateExp       = 10 * time.Minute
+	// oidcAudience keeps state tokens from passing as access tokens, see
+	// tokenAudience
+	oidcAudience = "oidc"
+)
+
+var (
+	errOIDCDisabled = errors.New("single sign-on is not configured")
+	errOIDCState    = errors.New("invalid or expired login state")
+	errOIDCEmail    = errors.New("identity provider did not return a verified email")
+)
+
+// oidcLoginHandler starts a login at the identity provider. state, nonce and
+// the PKCE verifier are kept in a signed, short-lived cookie so the server
+// stays stateless.
+func (app *application) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFoundResponse(w, r, errOIDCDisabled)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := app.oidc.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	stateToken, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"typ":      oidcStateTokenType,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"jti":      uuid.New().String(),
+		"exp":      time.Now().Add(oidcStateExp).Unix(),
+		"iat":      time.Now().Unix(),
+		"iss":      app.config.auth.token.iss,
+		"aud":      app.tokenAudience(oidcAudience),
+	})
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	app.setOIDCStateCookie(w, stateToken, oidcStateExp)
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallbackHandler finishes the login: the code is exchanged, the
+// identity resolved to a user (linking or creating one) and our own tokens
+// are issued, exactly as after a password login.
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFoundResponse(w, r, errOIDCDisabled)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		app.badRequestResponse(w, r, fmt.Errorf("identity provider: %s", e))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		app.badRequestResponse(w, r, errOIDCState)
+		return
+	}
+
+	// the state is single use
+	app.setOIDCStateCookie(w, "", -time.Second)
+
+	claims, err := app.validateOIDCState(cookie.Value, q.Get("state"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	identity, err := app.oidc.Exchange(ctx, q.Get("code"), verifier, nonce)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := app.userForIdentity(r, identity)
+	if err != nil {
+		switch err {
+		case store.ErrDuplicateEmail:
+			app.conflictResponse(w, r, err)
+		case errOIDCEmail:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.IsBanned() {
+		app.audit(r, &user.ID, user.Email, store.AuditLoginBanned)
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := app.generateMFAToken(user.ID)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		app.audit(r, &user.ID, user.Email, store.AuditMFAPending)
+
+		pending := MFAPending{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int(app.config.auth.mfa.pendingExp.Seconds()),
+		}
+
+		if err := app.jsonResponse(w, http.StatusAccepted, pending); err != nil {
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, &user.ID, user.Email, store.AuditLoginSucceeded)
+
+	tokens, err := app.issueTokens(ctx, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) validateOIDCState(stateToken, state string) (jwt.MapClaims, error) {
+	jwtToken, err := app.authenticator.ValidateTokenFor(stateToken, app.tokenAudience(oidcAudience))
+	if err != nil {
+		return nil, errOIDCState
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+
+	if typ, _ := claims["typ"].(string); typ != oidcStateTokenType {
+		return nil, errOIDCState
+	}
+
+	expected, _ := claims["state"].(string)
+	if state == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		return nil, errOIDCState
+	}
+
+	return claims, nil
+}
+
+// userForIdentity returns the user linked to identity. Unknown identities
+// are linked to the account with the same email if the provider verified
+// it, otherwise a new account is created.
+func (app *application) userForIdentity(r *http.Request, identity *oidc.Claims) (*store.User, error) {
+	ctx := r.Context()
+	provider := app.config.oidc.issuer
+
+	user, err := app.store.Identities.GetUser(ctx, provider, identity.Subject)
+	if err != store.ErrNotFound {
+		return user, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errOIDCEmail
+	}
+
+	user, err = app.store.Users.GetByEmail(ctx, identity.Email)
+	switch err {
+	case nil:
+		if err := app.store.Identities.Link(ctx, user.ID, provider, identity.Subject); err != nil {
+			return nil, err
+		}
+		return user, nil
+	case store.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	// nobody will ever type this password, it can only be reset
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	base := identity.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	user = &store.User{
+		Email: identity.Email,
+		Role:  store.Role{Name: "user"},
+	}
+	if err := user.Password.Set(password); err != nil {
+		return nil, err
+	}
+
+	// usernames are first come first served, add a suffix if it's taken
+	for attempt := 0; ; attempt++ {
+		user.Username = base
+		if attempt > 0 {
+			user.Username = fmt.Sprintf("%s-%s", base, uuid.New().String()[:6])
+		}
+
+		err = app.store.Identities.CreateUser(ctx, user, provider, identity.Subject)
+		if err != store.ErrDuplicateUsername || attempt == 3 {
+			return user, err
+		}
+	}
+}
+
+func (app *application) setOIDCStateCookie(w http.ResponseWriter, value string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/v1/authentication/oidc",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   app.config.env == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
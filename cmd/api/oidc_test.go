@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/oidc"
+	"github.com/Iowel/test-apps/internal/oidc/oidctest"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	idp, err := oidctest.NewIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	app := newTestApplication(t)
+	app.config.auth.token.exp = time.Minute
+	app.config.oidc.issuer = idp.URL
+	app.oidc = oidc.NewProvider(oidc.Config{
+		Issuer:      idp.URL,
+		ClientID:    "gophersocial",
+		RedirectURL: "http://localhost:8080/v1/authentication/oidc/callback",
+	}, idp.Client())
+
+	mux := app.mount()
+
+	gopher := oidctest.Identity{Subject: "42", Email: "gopher@example.com", EmailVerified: true}
+
+	// start logs in at the app and the provider, returning the callback
+	// request the browser would make
+	start := func(t *testing.T, identity oidctest.Identity) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/v1/authentication/oidc/login", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusFound, rr.Code)
+
+		code, state, err := idp.Authorize(rr.Header().Get("Location"), identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		callback, err := http.NewRequest(http.MethodGet, "/v1/authentication/oidc/callback?"+url.Values{
+			"code":  {code},
+			"state": {state},
+		}.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range rr.Result().Cookies() {
+			callback.AddCookie(c)
+		}
+
+		return callback
+	}
+
+	t.Run("should issue tokens after logging in at the provider", func(t *testing.T) {
+		rr := executeRequest(start(t, gopher), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var body struct {
+			Data TokenPair `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.AccessToken == "" || body.Data.RefreshToken == "" {
+			t.Errorf("Expected a token pair. Got %+v", body.Data)
+		}
+	})
+
+	t.Run("should reject a callback with another state", func(t *testing.T) {
+		req := start(t, gopher)
+
+		q := req.URL.Query()
+		q.Set("state", "forged")
+		req.URL.RawQuery = q.Encode()
+
+		checkResponseCode(t, http.StatusBadRequest, executeRequest(req, mux).Code)
+	})
+
+	t.Run("should reject a callback without the state cookie", func(t *testing.T) {
+		req := start(t, gopher)
+		req.Header.Del("Cookie")
+
+		checkResponseCode(t, http.StatusBadRequest, executeRequest(req, mux).Code)
+	})
+
+	t.Run("should not link unverified emails", func(t *testing.T) {
+		unverified := gopher
+		unverified.EmailVerified = false
+
+		checkResponseCode(t, http.StatusUnauthorized, executeRequest(start(t, unverified), mux).Code)
+	})
+}
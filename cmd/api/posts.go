@@ -56,10 +56,21 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+type PostWithComments struct {
+	*store.Post
+	CommentsNextCursor string `json:"comments_next_cursor"`
+}
+
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	cq := store.PaginatedCommentsQuery{
+		Limit:    20,
+		MaxDepth: app.config.comments.maxDepth,
+		ViewerID: getUserFromContext(r).ID,
+	}
+
+	comments, next, err := app.store.Comments.GetByPostID(r.Context(), post.ID, cq)
 	if err != nil {
 		app.statusInternalServerError(w, r, err)
 		return
@@ -67,7 +78,17 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	if err := app.attachReactions(r.Context(), []*store.Post{post}, getUserFromContext(r).ID); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	response := PostWithComments{
+		Post:               post,
+		CommentsNextCursor: app.encodeCursor(next, cursorScope("comments", post.ID, "")),
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
 		app.statusInternalServerError(w, r, err)
 		return
 	}
@@ -155,12 +176,56 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// a cached post may carry an outdated privacy setting of its author,
+		// cached users are invalidated as soon as it changes. Deactivated
+		// authors can't be loaded, their posts keep the setting they were
+		// loaded with, like without the cache.
+		if app.config.redisCfg.enabled {
+			author, err := app.getUser(ctx, post.UserID)
+			switch err {
+			case nil:
+				post.User.IsPrivate = author.IsPrivate
+			case store.ErrNotFound:
+			default:
+				app.statusInternalServerError(w, r, err)
+				return
+			}
+		}
+
+		// posts of private accounts only exist for their approved followers
+		visible, err := app.canViewPost(ctx, getUserFromContext(r), post)
+		if err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+
+		if !visible {
+			app.notFoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, postCtx, post)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func (app *application) canViewPost(ctx context.Context, viewer *store.User, post *store.Post) (bool, error) {
+	if !post.User.IsPrivate {
+		return true, nil
+	}
+
+	if viewer == nil {
+		return false, nil
+	}
+
+	if viewer.ID == post.UserID {
+		return true, nil
+	}
+
+	return app.store.Followers.IsFollowing(ctx, viewer.ID, post.UserID)
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(postCtx).(*store.Post)
 	return post
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Iowel/test-apps/internal/store"
+	"github.com/Iowel/test-apps/internal/store/cache"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostsContextMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		redisEnabled bool
+	}{
+		{"should show posts of deactivated authors", false},
+		{"should show posts of deactivated authors with redis enabled", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.redisCfg.enabled = tt.redisEnabled
+
+			cacheUsers := app.cacheStorage.Users.(*cache.MockUserStore)
+			cacheUsers.On("Get", int64(store.MockUnknownUserID)).Return(nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("postID", strconv.Itoa(store.MockDeactivatedAuthorPostID))
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, userCtx, &store.User{ID: 1})
+
+			handler := app.postsContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			rr := executeRequest(req.WithContext(ctx), handler)
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if tt.redisEnabled {
+				cacheUsers.AssertCalled(t, "Get", int64(store.MockUnknownUserID))
+			}
+			cacheUsers.AssertNotCalled(t, "Set", mock.Anything)
+		})
+	}
+}
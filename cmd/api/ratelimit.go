@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/ratelimiter"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// rateLimits holds a limiter for every rate limit policy.
+type rateLimits struct {
+	policies *ratelimiter.Policies
+	limiters map[string]ratelimiter.Limiter
+	// verifiedKeys holds the apiKeyBucket of the API keys AuthTokenMiddleware
+	// accepted, only they get a quota of their own
+	verifiedKeys sync.Map
+}
+
+// anonymousRoutes are the routes meant for clients without credentials.
+// They are always limited by IP, so credentials sent along can't buy more
+// password guesses or emails.
+var anonymousRoutes = []string{"/v1/authentication", "/v1/users/activate"}
+
+// newRateLimits loads the policies of cfg, or applies its quota to every
+// client if there are none. Replicas only share their limits when they're
+// kept in Redis.
+func newRateLimits(cfg ratelimiter.Config, rdb *redis.Client, logger *zap.SugaredLogger) (*rateLimits, error) {
+	policies := ratelimiter.DefaultPolicies(cfg)
+	if cfg.PoliciesFile != "" {
+		var err error
+		if policies, err = ratelimiter.LoadPolicies(cfg.PoliciesFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Redis && rdb == nil {
+		return nil, fmt.Errorf("the redis rate limiter requires REDIS_ENABLED")
+	}
+
+	rl := &rateLimits{
+		policies: policies,
+		limiters: make(map[string]ratelimiter.Limiter, len(policies.Policies)),
+	}
+
+	for name := range policies.Policies {
+		policyCfg := policies.Config(cfg, name)
+
+		if !cfg.Redis {
+			limiter, err := ratelimiter.New(policyCfg)
+			if err != nil {
+				return nil, err
+			}
+			rl.limiters[name] = limiter
+			continue
+		}
+
+		limiter, err := ratelimiter.NewRedisLimiter(rdb, policyCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		limiter.OnError = func(err error) {
+			logger.Errorw("rate limiter unavailable", "policy", name, "err", err, "fail_open", cfg.FailOpen)
+		}
+		rl.limiters[name] = limiter
+	}
+
+	return rl, nil
+}
+
+// allowRequest counts r against the quota of the policy of its route.
+func (app *application) allowRequest(r *http.Request) ratelimiter.Result {
+	name := app.rateLimits.policies.Match(r.Method, r.URL.Path)
+	policy := app.rateLimits.policies.Policies[name]
+
+	return app.rateLimits.limiters[name].Allow(app.rateLimitKey(r, policy.Key))
+}
+
+// rateLimitKey identifies the client of r. It runs before
+// AuthTokenMiddleware and on every request, so it never hits the database:
+// tokens are checked just enough to be sure nobody gets a fresh quota by
+// making them up, API keys only get one once AuthTokenMiddleware verified
+// them. Anything else is keyed by IP.
+func (app *application) rateLimitKey(r *http.Request, by string) string {
+	if by == ratelimiter.KeyByClient && !isAnonymousRoute(r.URL.Path) {
+		scheme, credential, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+
+		switch scheme {
+		case "Bearer":
+			if jwtToken, err := app.authenticator.ValidateToken(credential); err == nil {
+				claims := jwtToken.Claims.(jwt.MapClaims)
+				if typ, _ := claims["typ"].(string); typ == "" {
+					return fmt.Sprintf("user:%.f", claims["sub"])
+				}
+			}
+		case "ApiKey":
+			bucket := apiKeyBucket(credential)
+			if _, ok := app.rateLimits.verifiedKeys.Load(bucket); ok {
+				return bucket
+			}
+		}
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+func isAnonymousRoute(path string) bool {
+	for _, prefix := range anonymousRoutes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apiKeyBucket is the rate limit key of a plain API key.
+func apiKeyBucket(plainKey string) string {
+	hash := sha256.Sum256([]byte(plainKey))
+	return "apikey:" + hex.EncodeToString(hash[:8])
+}
+
+// verifyAPIKey gives a plain API key a quota of its own from now on, or
+// takes it away again if the key was rejected, e.g. after it was deleted.
+func (app *application) verifyAPIKey(plainKey string, ok bool) {
+	if app.rateLimits == nil {
+		return
+	}
+
+	if ok {
+		app.rateLimits.verifiedKeys.Store(apiKeyBucket(plainKey), struct{}{})
+	} else {
+		app.rateLimits.verifiedKeys.Delete(apiKeyBucket(plainKey))
+	}
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, res ratelimiter.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+}
+
+// seconds formats d as whole seconds, rounded up so clients don't retry
+// too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/ratelimiter"
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimits.json")
+	err := os.WriteFile(path, []byte(`{
+		"policies": {
+			"default": {"requests": 2, "window": "1m", "key": "client"},
+			"auth": {"requests": 1, "window": "1m", "key": "ip"}
+		},
+		"routes": [{"method": "POST", "path": "/v1/authentication/token", "policy": "auth"}]
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t)
+	app.config.rateLimiter = ratelimiter.Config{Enabled: true, PoliciesFile: path}
+
+	app.rateLimits, err = newRateLimits(app.config.rateLimiter, nil, app.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := app.mount()
+
+	request := func(method, path, remoteAddr, authorization string) *http.Response {
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = remoteAddr
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should limit by IP regardless of the port", func(t *testing.T) {
+		res := request(http.MethodPost, "/v1/authentication/token", "10.0.0.1:40000", "")
+		if got := res.Header.Get("RateLimit-Remaining"); got != "0" {
+			t.Errorf("Expected no remaining requests. Got %q", got)
+		}
+
+		res = request(http.MethodPost, "/v1/authentication/token", "10.0.0.1:40001", "")
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+
+		if got := res.Header.Get("Retry-After"); got != "60" {
+			t.Errorf("Expected to retry after 60 seconds. Got %q", got)
+		}
+	})
+
+	t.Run("should give every user their own quota", func(t *testing.T) {
+		token := func(userID int64) string {
+			token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+				"sub": userID,
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return "Bearer " + token
+		}
+
+		for i := 0; i < 2; i++ {
+			res := request(http.MethodGet, "/v1/health", "10.0.0.2:40000", token(1))
+			if res.StatusCode == http.StatusTooManyRequests {
+				t.Fatalf("Request %d was limited", i)
+			}
+			if got := res.Header.Get("RateLimit-Limit"); got != "2" {
+				t.Errorf("Expected a limit of 2. Got %q", got)
+			}
+		}
+
+		res := request(http.MethodGet, "/v1/health", "10.0.0.2:40000", token(1))
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+
+		res = request(http.MethodGet, "/v1/health", "10.0.0.2:40000", token(2))
+		if res.StatusCode == http.StatusTooManyRequests {
+			t.Error("Expected another user on the same IP not to be limited")
+		}
+
+		// made up tokens don't get a quota of their own
+		res = request(http.MethodGet, "/v1/health", "10.0.0.2:40000", "Bearer made-up")
+		if res.StatusCode == http.StatusTooManyRequests {
+			t.Error("Expected the IP to have its own quota")
+		}
+		res = request(http.MethodGet, "/v1/health", "10.0.0.2:40000", "Bearer made-up-too")
+		if res.StatusCode == http.StatusTooManyRequests {
+			t.Error("Expected the IP to have its own quota")
+		}
+		res = request(http.MethodGet, "/v1/health", "10.0.0.2:40000", "Bearer made-up-again")
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+
+	t.Run("should give verified API keys their own quota on any IP", func(t *testing.T) {
+		// the first request verifies the key and still counts for the IP
+		res := request(http.MethodGet, "/v1/users/25", "10.0.0.3:40000", "ApiKey "+store.MockAPIKey)
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		for i, addr := range []string{"10.0.0.4:40000", "10.0.0.5:40000"} {
+			res := request(http.MethodGet, "/v1/health", addr, "ApiKey "+store.MockAPIKey)
+			if res.StatusCode == http.StatusTooManyRequests {
+				t.Fatalf("Request %d was limited", i)
+			}
+		}
+
+		res = request(http.MethodGet, "/v1/health", "10.0.0.6:40000", "ApiKey "+store.MockAPIKey)
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+
+	t.Run("should limit made up API keys by IP", func(t *testing.T) {
+		for _, key := range []string{"gsk_made-up", "gsk_made-up-too"} {
+			res := request(http.MethodGet, "/v1/users/25", "10.0.0.7:40000", "ApiKey "+key)
+			checkResponseCode(t, http.StatusUnauthorized, res.StatusCode)
+		}
+
+		res := request(http.MethodGet, "/v1/users/25", "10.0.0.7:40000", "ApiKey gsk_made-up-again")
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+
+	t.Run("should limit anonymous routes by IP", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res := request(http.MethodGet, "/v1/users/activate/token", "10.0.0.8:40000", "ApiKey "+store.MockWriteAPIKey)
+			if res.StatusCode == http.StatusTooManyRequests {
+				t.Fatalf("Request %d was limited", i)
+			}
+		}
+
+		res := request(http.MethodGet, "/v1/users/activate/token", "10.0.0.8:40000", "ApiKey "+store.MockAPIKey)
+		checkResponseCode(t, http.StatusTooManyRequests, res.StatusCode)
+	})
+}
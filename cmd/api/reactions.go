@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) putReactionHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	kind := chi.URLParam(r, "kind")
+	if !store.IsReactionKind(kind) {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown reaction kind %q", kind))
+		return
+	}
+
+	if err := app.store.Reactions.Add(r.Context(), post.ID, user.ID, kind); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteReactionHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	kind := chi.URLParam(r, "kind")
+	if !store.IsReactionKind(kind) {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown reaction kind %q", kind))
+		return
+	}
+
+	if err := app.store.Reactions.Remove(r.Context(), post.ID, user.ID, kind); err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachReactions fills in the reactions of posts as seen by viewerID.
+func (app *application) attachReactions(ctx context.Context, posts []*store.Post, viewerID int64) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+
+	reactions, err := app.store.Reactions.GetByPostIDs(ctx, ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		p.Reactions = reactions[p.ID]
+		if p.Reactions == nil {
+			p.Reactions = store.Reactions{}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReactions(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	reactionStore := app.store.Reactions.(*store.MockReactionStore)
+	reactionStore.On("Add", int64(1), int64(7), "love").Return(nil)
+	reactionStore.On("Remove", int64(1), int64(7), "love").Return(nil)
+
+	token, err := app.authenticator.GenerateToken(jwt.MapClaims{
+		"sub": int64(7),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	react := func(t *testing.T, method, kind string) int {
+		req, err := http.NewRequest(method, "/v1/posts/1/reactions/"+kind, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should add and remove reactions of the user", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, react(t, http.MethodPut, "love"))
+		checkResponseCode(t, http.StatusNoContent, react(t, http.MethodDelete, "love"))
+
+		reactionStore.AssertExpectations(t)
+	})
+
+	t.Run("should reject unknown reaction kinds", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, react(t, http.MethodPut, "meh"))
+		checkResponseCode(t, http.StatusBadRequest, react(t, http.MethodDelete, "meh"))
+
+		reactionStore.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, "meh")
+		reactionStore.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything, "meh")
+	})
+}
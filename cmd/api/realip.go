@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxies parses the addresses and CIDR ranges of the proxies
+// whose X-Forwarded-For and X-Real-IP headers are believed.
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// realIPMiddleware replaces the RemoteAddr of requests relayed by a trusted
+// proxy with the address of the client the proxy saw. Anybody can send the
+// headers, so they are ignored on connections from anywhere else; otherwise
+// clients could pick their own rate limit key.
+func (app *application) realIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := app.forwardedIP(r); ip != "" {
+			r.RemoteAddr = ip
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the client address forwarded to r, or "" if r didn't
+// come from a trusted proxy. X-Forwarded-For is read from the right, the
+// entries left of the first untrusted hop may be made up by the client.
+func (app *application) forwardedIP(r *http.Request) string {
+	if !app.isTrustedProxy(clientIP(r)) {
+		return ""
+	}
+
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			return addr.String()
+		}
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return ""
+		}
+		if !app.isTrustedProxy(addr.String()) {
+			return addr.String()
+		}
+	}
+
+	return ""
+}
+
+func (app *application) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, proxy := range app.config.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the IP address of r without the port, otherwise every
+// connection would be a new client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	app := newTestApplication(t)
+
+	var err error
+	app.config.trustedProxies, err = parseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "should ignore the headers of untrusted peers",
+			remoteAddr: "203.0.113.7:40000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.1"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "should use X-Real-IP of trusted proxies",
+			remoteAddr: "10.0.0.1:40000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "should skip trusted hops of X-Forwarded-For",
+			remoteAddr: "10.0.0.1:40000",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.1, 192.168.1.1"},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "should keep the peer without forwarded headers",
+			remoteAddr: "192.168.1.1:40000",
+			expected:   "192.168.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			var got string
+			handler := app.realIPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+			executeRequest(req, handler)
+
+			if got != tt.expected {
+				t.Errorf("Expected client IP %q. Got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := parseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+}
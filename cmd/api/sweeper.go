@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"time"
+)
+
+var (
+	invitationsPurged     = expvar.NewInt("invitations_purged")
+	invitationsLastSweep  = expvar.NewString("invitations_last_sweep")
+	invitationsSweepFails = expvar.NewInt("invitations_sweep_errors")
+
+	tokensPurged     = expvar.NewInt("tokens_purged")
+	tokensSweepFails = expvar.NewInt("tokens_sweep_errors")
+)
+
+// sweepExpired periodically deletes what has expired: the users that never
+// activated their account, so their username and email can be registered
+// again, and the refresh tokens and revoked access tokens nobody can use
+// anymore. It runs until ctx is cancelled.
+func (app *application) sweepExpired(ctx context.Context) {
+	ticker := time.NewTicker(app.config.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.purgeExpiredInvitations(ctx)
+			app.purgeExpiredTokens(ctx)
+		}
+	}
+}
+
+func (app *application) purgeExpiredInvitations(ctx context.Context) {
+	purged, err := app.store.Users.PurgeExpiredInvitations(ctx, app.config.invitations.grace)
+	if err != nil {
+		invitationsSweepFails.Add(1)
+		app.logger.Errorw("failed to purge expired invitations", "error", err)
+		return
+	}
+
+	invitationsPurged.Add(purged)
+	invitationsLastSweep.Set(time.Now().UTC().Format(time.RFC3339))
+
+	if purged > 0 {
+		app.logger.Infow("purged expired invitations", "users", purged)
+	}
+}
+
+func (app *application) purgeExpiredTokens(ctx context.Context) {
+	purged, err := app.store.Tokens.PurgeExpired(ctx)
+	if err != nil {
+		tokensSweepFails.Add(1)
+		app.logger.Errorw("failed to purge expired tokens", "error", err)
+		return
+	}
+
+	tokensPurged.Add(purged)
+
+	if purged > 0 {
+		app.logger.Infow("purged expired tokens", "tokens", purged)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+)
+
+func TestPurgeExpiredInvitations(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.invitations.grace = 48 * time.Hour
+	ctx := context.Background()
+
+	userStore := app.store.Users.(*store.MockUserStore)
+
+	t.Run("should purge with the configured grace and count the users", func(t *testing.T) {
+		userStore.On("PurgeExpiredInvitations", 48*time.Hour).Return(int64(2), nil).Once()
+		before := invitationsPurged.Value()
+		invitationsLastSweep.Set("")
+
+		app.purgeExpiredInvitations(ctx)
+
+		if n := invitationsPurged.Value() - before; n != 2 {
+			t.Errorf("Expected 2 purged users. Got %d", n)
+		}
+		if invitationsLastSweep.Value() == "" {
+			t.Error("Expected the time of the sweep to be recorded")
+		}
+	})
+
+	t.Run("should count failed sweeps", func(t *testing.T) {
+		userStore.On("PurgeExpiredInvitations", 48*time.Hour).Return(int64(0), errors.New("connection refused")).Once()
+		before := invitationsSweepFails.Value()
+		invitationsLastSweep.Set("")
+
+		app.purgeExpiredInvitations(ctx)
+
+		if n := invitationsSweepFails.Value() - before; n != 1 {
+			t.Errorf("Expected 1 failed sweep. Got %d", n)
+		}
+		if invitationsLastSweep.Value() != "" {
+			t.Error("Expected a failed sweep not to be recorded as the last one")
+		}
+	})
+
+	userStore.AssertExpectations(t)
+}
+
+func TestPurgeExpiredTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ctx := context.Background()
+
+	tokenStore := app.store.Tokens.(*store.MockTokenStore)
+
+	t.Run("should count the purged tokens", func(t *testing.T) {
+		tokenStore.On("PurgeExpired").Return(int64(3), nil).Once()
+		before := tokensPurged.Value()
+
+		app.purgeExpiredTokens(ctx)
+
+		if n := tokensPurged.Value() - before; n != 3 {
+			t.Errorf("Expected 3 purged tokens. Got %d", n)
+		}
+	})
+
+	t.Run("should count failed sweeps", func(t *testing.T) {
+		tokenStore.On("PurgeExpired").Return(int64(0), errors.New("connection refused")).Once()
+		before, purged := tokensSweepFails.Value(), tokensPurged.Value()
+
+		app.purgeExpiredTokens(ctx)
+
+		if n := tokensSweepFails.Value() - before; n != 1 {
+			t.Errorf("Expected 1 failed sweep. Got %d", n)
+		}
+		if tokensPurged.Value() != purged {
+			t.Error("Expected no purged tokens to be counted")
+		}
+	})
+
+	tokenStore.AssertExpectations(t)
+}
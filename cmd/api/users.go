@@ -1,18 +1,34 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/Iowel/test-apps/internal/mailer"
 	"github.com/Iowel/test-apps/internal/store"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type userKey string
 
 const userCtx userKey = "user"
 
+var errInvalidUserID = errors.New("invalid user id")
+
+type UserProfile struct {
+	*store.User
+	FollowersCount int  `json:"followers_count"`
+	FollowingCount int  `json:"following_count"`
+	IsFollowing    bool `json:"is_following"`
+}
+
 // GetUser godoc
 //
 //	@Summary		Fetches a user profile
@@ -21,7 +37,7 @@ const userCtx userKey = "user"
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	UserProfile
 //	@Failure		400	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
@@ -29,8 +45,8 @@ const userCtx userKey = "user"
 //	@Router			/users/{id} [get]
 
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
-	if err != nil || userID < 1 {
+	userID, err := readUserIDParam(r, "userID")
+	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -49,7 +65,122 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+	// blocked users are hidden from each other
+	blocked, err := app.store.Blocks.IsBlocked(ctx, getUserFromContext(r).ID, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if blocked {
+		app.notFoundResponse(w, r, store.ErrBlocked)
+		return
+	}
+
+	followers, following, err := app.store.Followers.Counts(ctx, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	isFollowing, err := app.store.Followers.IsFollowing(ctx, getUserFromContext(r).ID, user.ID)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	profile := UserProfile{
+		User:           user,
+		FollowersCount: followers,
+		FollowingCount: following,
+		IsFollowing:    isFollowing,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, profile); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+// readUserIDParam reads the ID of a user from the URL parameter name.
+func readUserIDParam(r *http.Request, name string) (int64, error) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil || userID < 1 {
+		return 0, errInvalidUserID
+	}
+
+	return userID, nil
+}
+
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := readUserIDParam(r, "userID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// an empty list would hide that the user doesn't exist
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	scope := cursorScope("followers", userID, "")
+
+	q, err := app.readPaginatedQuery(r, scope)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	followers, next, err := app.store.Followers.GetFollowers(r.Context(), userID, q)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, followers, next, scope); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := readUserIDParam(r, "userID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// an empty list would hide that the user doesn't exist
+	if _, err := app.getUser(r.Context(), userID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	scope := cursorScope("following", userID, "")
+
+	q, err := app.readPaginatedQuery(r, scope)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	following, next, err := app.store.Followers.GetFollowing(r.Context(), userID, q)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, following, next, scope); err != nil {
 		app.statusInternalServerError(w, r, err)
 	}
 }
@@ -57,7 +188,7 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
 	followerUser := getUserFromContext(r)
 
-	followedID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	followedID, err := readUserIDParam(r, "userID")
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -65,11 +196,45 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
+	followed, err := app.getUser(ctx, followedID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	// private accounts have to approve their followers first
+	if followed.IsPrivate && followed.ID != followerUser.ID {
+		if err := app.store.FollowRequests.Create(ctx, followerUser.ID, followed.ID); err != nil {
+			switch err {
+			case store.ErrConflict:
+				app.conflictResponse(w, r, err)
+			case store.ErrBlocked:
+				app.forbiddenResponse(w, r)
+			default:
+				app.statusInternalServerError(w, r, err)
+			}
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
 	if err := app.store.Followers.Follow(ctx, followerUser.ID, followedID); err != nil {
 		switch err {
 		case store.ErrConflict:
 			app.conflictResponse(w, r, err)
 			return
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+			return
 		default:
 			app.statusInternalServerError(w, r, err)
 			return
@@ -84,7 +249,7 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 func (app *application) unFollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	followerUser := getUserFromContext(r)
 
-	unFollowedID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	unFollowedID, err := readUserIDParam(r, "userID")
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -102,6 +267,95 @@ func (app *application) unFollowUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+type UpdateUserPayload struct {
+	IsPrivate *bool `json:"is_private"`
+}
+
+func (app *application) updateCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload UpdateUserPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if payload.IsPrivate != nil {
+		if err := app.store.Users.SetPrivate(ctx, user.ID, *payload.IsPrivate); err != nil {
+			app.statusInternalServerError(w, r, err)
+			return
+		}
+		user.IsPrivate = *payload.IsPrivate
+
+		app.invalidateUser(ctx, user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	scope := cursorScope("follow-requests", user.ID, "")
+
+	q, err := app.readPaginatedQuery(r, scope)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	requests, next, err := app.store.FollowRequests.GetIncoming(r.Context(), user.ID, q)
+	if err != nil {
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, requests, next, scope); err != nil {
+		app.statusInternalServerError(w, r, err)
+	}
+}
+
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, app.store.FollowRequests.Approve)
+}
+
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, app.store.FollowRequests.Reject)
+}
+
+func (app *application) answerFollowRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, userID, requesterID int64) error) {
+	user := getUserFromContext(r)
+
+	requesterID, err := readUserIDParam(r, "requesterID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := answer(r.Context(), user.ID, requesterID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+		default:
+			app.statusInternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // func (app *application) userContextMiddleware(next http.Handler) http.Handler {
 // 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 // 		userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
@@ -155,3 +409,62 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	// 	app.statusInternalServerError(w, r, err)
 	// }
 }
+
+type ResendActivationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// resendActivationHandler sends a new activation link to a user that hasn't
+// activated their account yet. Like the password reset, it answers the same
+// for unknown and already active emails.
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendActivationPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(plainToken))
+	hashToken := hex.EncodeToString(hash[:])
+
+	user, err := app.store.Users.ResendInvitation(r.Context(), payload.Email, hashToken, app.config.mail.exp)
+	switch err {
+	case nil:
+	case store.ErrNotFound:
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		app.statusInternalServerError(w, r, err)
+		return
+	}
+
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: fmt.Sprintf("%s/v1/users/activate/%s", app.config.frontendURL, plainToken),
+	}
+
+	isProdEnv := app.config.env == "production"
+
+	go func() {
+		status, err := app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv)
+		if err != nil {
+			app.logger.Errorw("error resending activation email", "error", err)
+			return
+		}
+
+		app.logger.Infow("Email sent", "status code", status)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
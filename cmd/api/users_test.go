@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
+
+	"github.com/Iowel/test-apps/internal/store"
 )
 
 func TestGetUser(t *testing.T) {
@@ -38,3 +41,102 @@ func TestGetUser(t *testing.T) {
 		checkResponseCode(t, http.StatusOK, rr.Code)
 	})
 }
+
+func TestGetFollowers(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(path string) int {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, listing := range []string{"followers", "following"} {
+		t.Run("should list the "+listing+" of a user", func(t *testing.T) {
+			checkResponseCode(t, http.StatusOK, list("/v1/users/6/"+listing))
+		})
+
+		t.Run("should not list the "+listing+" of unknown users", func(t *testing.T) {
+			checkResponseCode(t, http.StatusNotFound, list(fmt.Sprintf("/v1/users/%d/%s", store.MockUnknownUserID, listing)))
+		})
+
+		t.Run("should reject invalid user IDs in "+listing, func(t *testing.T) {
+			checkResponseCode(t, http.StatusBadRequest, list("/v1/users/0/"+listing))
+		})
+	}
+}
+
+func TestAnswerFollowRequest(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answer := func(path string) int {
+		req, err := http.NewRequest(http.MethodPut, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, action := range []string{"approve", "reject"} {
+		t.Run("should "+action+" follow requests", func(t *testing.T) {
+			checkResponseCode(t, http.StatusNoContent, answer("/v1/users/me/follow-requests/6/"+action))
+		})
+
+		t.Run("should reject invalid requesters to "+action, func(t *testing.T) {
+			checkResponseCode(t, http.StatusBadRequest, answer("/v1/users/me/follow-requests/0/"+action))
+		})
+	}
+
+	t.Run("should not approve requests across a block", func(t *testing.T) {
+		checkResponseCode(t, http.StatusForbidden, answer(fmt.Sprintf("/v1/users/me/follow-requests/%d/approve", store.MockBlockedUserID)))
+	})
+}
+
+func TestFollowUser(t *testing.T) {
+	app := newTestApplication(t)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follow := func(path string) int {
+		req, err := http.NewRequest(http.MethodPut, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, action := range []string{"follow", "unfollow"} {
+		for _, userID := range []string{"0", "-1", "abc"} {
+			t.Run("should reject user "+userID+" to "+action, func(t *testing.T) {
+				checkResponseCode(t, http.StatusBadRequest, follow("/v1/users/"+userID+"/"+action))
+			})
+		}
+	}
+}
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_comments_parent_id;
+
+ALTER TABLE
+    comments
+DROP COLUMN depth;
+
+ALTER TABLE
+    comments
+DROP COLUMN parent_id;
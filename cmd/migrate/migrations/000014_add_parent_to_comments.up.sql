@@ -0,0 +1,11 @@
+ALTER TABLE
+    comments
+ADD
+    COLUMN parent_id bigint REFERENCES comments (id) ON DELETE CASCADE;
+
+ALTER TABLE
+    comments
+ADD
+    COLUMN depth INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reactions (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    kind varchar(32) NOT NULL,
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, user_id, kind),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_post_id ON reactions (post_id);
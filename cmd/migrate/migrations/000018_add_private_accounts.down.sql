@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS follow_requests;
+
+ALTER TABLE
+    users
+DROP COLUMN is_private;
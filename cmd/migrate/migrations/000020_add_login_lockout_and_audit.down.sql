@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS auth_audit_log;
+
+ALTER TABLE
+    users
+DROP COLUMN locked_until;
+
+ALTER TABLE
+    users
+DROP COLUMN failed_login_attempts;
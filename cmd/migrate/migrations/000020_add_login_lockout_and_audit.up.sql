@@ -0,0 +1,20 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN failed_login_attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN locked_until TIMESTAMP(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+    id bigserial PRIMARY KEY,
+    user_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    email citext NOT NULL DEFAULT '',
+    event varchar(64) NOT NULL,
+    ip varchar(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_user_id ON auth_audit_log (user_id);
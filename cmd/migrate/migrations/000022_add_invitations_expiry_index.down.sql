@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_invitations_expiry;
+DROP INDEX IF EXISTS idx_user_invitations_user_id;
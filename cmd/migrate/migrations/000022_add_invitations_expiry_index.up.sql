@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_user_invitations_user_id ON user_invitations (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_invitations_expiry ON user_invitations (expiry);
@@ -0,0 +1,23 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN totp_secret bytea;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    user_id bigint NOT NULL,
+    code_hash text NOT NULL,
+    used_at TIMESTAMP(0) with time zone,
+
+    PRIMARY KEY (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
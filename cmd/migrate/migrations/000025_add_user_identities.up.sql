@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider varchar(255) NOT NULL,
+    subject varchar(255) NOT NULL,
+    user_id bigint NOT NULL,
+    created_at TIMESTAMP(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id bigint NOT NULL,
+    permission_id bigint NOT NULL,
+
+    PRIMARY KEY (role_id, permission_id),
+    FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('posts:update:any', 'Update posts of other users'),
+    ('posts:delete:any', 'Delete posts of other users'),
+    ('comments:update:any', 'Update comments of other users'),
+    ('comments:delete:any', 'Delete comments of other users'),
+    ('users:read:any', 'List users and see their account details'),
+    ('users:update:role', 'Change the role of a user'),
+    ('users:ban', 'Ban and unban users'),
+    ('users:logout', 'Sign users out of every session');
+
+-- the grants of the previous level hierarchy: moderators may update
+-- content of others, admins may also delete it and manage users
+INSERT INTO
+    role_permissions (role_id, permission_id)
+SELECT
+    r.id,
+    p.id
+FROM
+    roles r
+    JOIN permissions p ON p.name IN ('posts:update:any', 'comments:update:any')
+WHERE
+    r.name = 'moderator';
+
+INSERT INTO
+    role_permissions (role_id, permission_id)
+SELECT
+    r.id,
+    p.id
+FROM
+    roles r
+    CROSS JOIN permissions p
+WHERE
+    r.name = 'admin';
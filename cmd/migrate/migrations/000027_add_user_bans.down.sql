@@ -0,0 +1,15 @@
+ALTER TABLE
+    users
+DROP COLUMN sessions_revoked_at;
+
+ALTER TABLE
+    users
+DROP COLUMN ban_reason;
+
+ALTER TABLE
+    users
+DROP COLUMN banned_until;
+
+ALTER TABLE
+    users
+DROP COLUMN banned_at;
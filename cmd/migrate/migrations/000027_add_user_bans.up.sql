@@ -0,0 +1,19 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN banned_at TIMESTAMP(0) with time zone;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN banned_until TIMESTAMP(0) with time zone;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN ban_reason TEXT;
+
+ALTER TABLE
+    users
+ADD
+    COLUMN sessions_revoked_at TIMESTAMP(0) with time zone;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_revoked_tokens_expiry;
+DROP INDEX IF EXISTS idx_refresh_tokens_expiry;
@@ -0,0 +1,4 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN deactivated_at TIMESTAMP(0) with time zone;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_auth_audit_log_actor_id;
+
+ALTER TABLE
+    auth_audit_log
+DROP COLUMN actor_id;
@@ -0,0 +1,6 @@
+ALTER TABLE
+    auth_audit_log
+ADD
+    COLUMN actor_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_actor_id ON auth_audit_log (actor_id);
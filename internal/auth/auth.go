@@ -4,5 +4,9 @@ import "github.com/golang-jwt/jwt/v5"
 
 type Authenticator interface {
 	GenerateToken(claims jwt.MapClaims) (string, error)
+	// ValidateToken validates an access token
 	ValidateToken(token string) (*jwt.Token, error)
+	// ValidateTokenFor validates a token issued for another audience, e.g.
+	// one that must never pass as an access token
+	ValidateTokenFor(token, aud string) (*jwt.Token, error)
 }
@@ -28,6 +28,10 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.MapClaims) (string, error) {
 }
 
 func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return a.ValidateTokenFor(token, a.aud)
+}
+
+func (a *JWTAuthenticator) ValidateTokenFor(token, aud string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
@@ -36,8 +40,8 @@ func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 		return []byte(a.secret), nil
 	},
 		jwt.WithExpirationRequired(),
-		jwt.WithAudience(a.aud),
-		jwt.WithIssuer(a.aud),
+		jwt.WithAudience(aud),
+		jwt.WithIssuer(a.iss),
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
 	)
 }
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySetPublisher is implemented by authenticators whose tokens can be
+// verified by third parties from the published public keys.
+type KeySetPublisher interface {
+	JWKS() JWKS
+}
+
+// KeySetAuthenticator signs tokens with an asymmetric key (RS256 or EdDSA)
+// and names it in the "kid" header. Tokens are verified against every key
+// of the set, which allows rotating the signing key without logging
+// everybody out.
+type KeySetAuthenticator struct {
+	signing *Key
+	keys    map[string]*Key
+	aud     string
+	iss     string
+}
+
+func NewKeySetAuthenticator(signing *Key, verification []*Key, aud, iss string) (*KeySetAuthenticator, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, errors.New("a private signing key is required")
+	}
+
+	keys := map[string]*Key{signing.ID: signing}
+	for _, k := range verification {
+		if _, ok := keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		keys[k.ID] = k
+	}
+
+	return &KeySetAuthenticator{signing, keys, aud, iss}, nil
+}
+
+func (a *KeySetAuthenticator) GenerateToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(a.signing.Method, claims)
+	token.Header["kid"] = a.signing.ID
+
+	tokenString, err := token.SignedString(a.signing.Private)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+func (a *KeySetAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return a.ValidateTokenFor(token, a.aud)
+}
+
+func (a *KeySetAuthenticator) ValidateTokenFor(token, aud string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		return key.Public, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(aud),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name, jwt.SigningMethodEdDSA.Alg()}),
+	)
+}
+
+func (a *KeySetAuthenticator) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(a.keys))}
+
+	// the current signing key first, then the ones being rotated out
+	jwks.Keys = append(jwks.Keys, a.signing.JWK())
+
+	ids := make([]string, 0, len(a.keys))
+	for id := range a.keys {
+		if id != a.signing.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		jwks.Keys = append(jwks.Keys, a.keys[id].JWK())
+	}
+
+	return jwks
+}
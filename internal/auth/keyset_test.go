@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testClaimsFor(sub int64) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": sub,
+		"aud": "test-aud",
+		"iss": "test-aud",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := NewKey(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeySetAuthenticator(t *testing.T) {
+	oldKey := newRSAKey(t, "2025-01")
+	newKey := newEd25519Key(t, "2025-06")
+
+	before, err := NewKeySetAuthenticator(oldKey, nil, "test-aud", "test-aud")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotated: signs with the new key, still accepts the old one
+	after, err := NewKeySetAuthenticator(newKey, []*Key{{ID: oldKey.ID, Method: oldKey.Method, Public: oldKey.Public}}, "test-aud", "test-aud")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should validate its own tokens", func(t *testing.T) {
+		for _, a := range []*KeySetAuthenticator{before, after} {
+			token, err := a.GenerateToken(testClaimsFor(25))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := a.ValidateToken(token); err != nil {
+				t.Errorf("Expected token signed with %s to validate. Got %v", a.signing.ID, err)
+			}
+		}
+	})
+
+	t.Run("should accept tokens of the previous key after rotation", func(t *testing.T) {
+		token, err := before.GenerateToken(testClaimsFor(25))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := after.ValidateToken(token); err != nil {
+			t.Errorf("Expected token of the rotated out key to validate. Got %v", err)
+		}
+	})
+
+	t.Run("should reject tokens of unknown keys", func(t *testing.T) {
+		token, err := after.GenerateToken(testClaimsFor(25))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := before.ValidateToken(token); err == nil {
+			t.Error("Expected token of an unknown key to be rejected")
+		}
+	})
+
+	t.Run("should reject HS256 tokens", func(t *testing.T) {
+		token, err := NewJWTAuthenticator("secret", "test-aud", "test-aud").GenerateToken(testClaimsFor(25))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := after.ValidateToken(token); err == nil {
+			t.Error("Expected HS256 token to be rejected")
+		}
+	})
+
+	t.Run("should only accept tokens for their audience", func(t *testing.T) {
+		claims := testClaimsFor(25)
+		claims["aud"] = "test-aud/mfa"
+
+		token, err := after.GenerateToken(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := after.ValidateToken(token); err == nil {
+			t.Error("Expected a token for another audience to be rejected")
+		}
+		if _, err := after.ValidateTokenFor(token, "test-aud/mfa"); err != nil {
+			t.Errorf("Expected a token for its audience to be valid. Got %v", err)
+		}
+	})
+
+	t.Run("should publish every verification key", func(t *testing.T) {
+		jwks := after.JWKS()
+
+		if len(jwks.Keys) != 2 {
+			t.Fatalf("Expected 2 keys. Got %d", len(jwks.Keys))
+		}
+
+		if k := jwks.Keys[0]; k.Kid != newKey.ID || k.Kty != "OKP" || k.Alg != "EdDSA" || k.X == "" {
+			t.Errorf("Unexpected signing key %+v", k)
+		}
+
+		if k := jwks.Keys[1]; k.Kid != oldKey.ID || k.Kty != "RSA" || k.Alg != "RS256" || k.N == "" || k.E != "AQAB" {
+			t.Errorf("Unexpected rotated key %+v", k)
+		}
+	})
+}
@@ -19,7 +19,11 @@ type TestAuthenticator struct {
 }
 
 func (a *TestAuthenticator) GenerateToken(claims jwt.MapClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims)
+	if claims == nil {
+		claims = testClaims
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, _ := token.SignedString([]byte(secret))
 
@@ -31,3 +35,9 @@ func (a *TestAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 		return []byte(secret), nil
 	})
 }
+
+func (a *TestAuthenticator) ValidateTokenFor(token, aud string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithAudience(aud))
+}
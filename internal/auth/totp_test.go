@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(secret, tt.code, time.Unix(tt.time, 0))
+		if !ok {
+			t.Errorf("Expected %s to be valid at %d", tt.code, tt.time)
+			continue
+		}
+
+		if want := tt.time / 30; step != want {
+			t.Errorf("Expected step %d. Got %d", want, step)
+		}
+	}
+
+	t.Run("should allow one period of clock skew", func(t *testing.T) {
+		if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+30, 0)); !ok {
+			t.Error("Expected the previous code to be valid")
+		}
+
+		if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+60, 0)); ok {
+			t.Error("Expected a code two periods old to be rejected")
+		}
+	})
+
+	t.Run("should reject malformed codes", func(t *testing.T) {
+		for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+			if _, ok := ValidateTOTP(secret, code, time.Unix(59, 0)); ok {
+				t.Errorf("Expected %q to be rejected", code)
+			}
+		}
+	})
+}
+
+func TestSealOpen(t *testing.T) {
+	sealed, err := Seal("key", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := Open("key", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(plain) != "secret" {
+		t.Errorf("Expected secret. Got %s", plain)
+	}
+
+	if _, err := Open("other key", sealed); err == nil {
+		t.Error("Expected opening with another key to fail")
+	}
+}
@@ -0,0 +1,84 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Iowel/test-apps/internal/oidc"
+	"github.com/Iowel/test-apps/internal/oidc/oidctest"
+)
+
+func TestProvider(t *testing.T) {
+	idp, err := oidctest.NewIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      idp.URL,
+		ClientID:    "gophersocial",
+		RedirectURL: "http://localhost:8080/v1/authentication/oidc/callback",
+	}, idp.Client())
+
+	gopher := oidctest.Identity{Subject: "42", Email: "gopher@example.com", EmailVerified: true}
+	ctx := context.Background()
+
+	login := func(t *testing.T, verifier string) (code, state string) {
+		authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, state, err = idp.Authorize(authURL, gopher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code, state
+	}
+
+	t.Run("should exchange a code for the identity", func(t *testing.T) {
+		code, state := login(t, "verifier")
+		if state != "state" {
+			t.Errorf("Expected state to round trip. Got %q", state)
+		}
+
+		claims, err := provider.Exchange(ctx, code, "verifier", "nonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims.Subject != gopher.Subject || claims.Email != gopher.Email || !claims.EmailVerified {
+			t.Errorf("Unexpected claims %+v", claims)
+		}
+	})
+
+	t.Run("should fail without the PKCE verifier", func(t *testing.T) {
+		code, _ := login(t, "verifier")
+
+		if _, err := provider.Exchange(ctx, code, "another verifier", "nonce"); err == nil {
+			t.Error("Expected the exchange to fail")
+		}
+	})
+
+	t.Run("should not redeem a code twice", func(t *testing.T) {
+		code, _ := login(t, "verifier")
+
+		if _, err := provider.Exchange(ctx, code, "verifier", "nonce"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := provider.Exchange(ctx, code, "verifier", "nonce"); err == nil {
+			t.Error("Expected the second exchange to fail")
+		}
+	})
+
+	t.Run("should reject an id token for another nonce", func(t *testing.T) {
+		code, _ := login(t, "verifier")
+
+		if _, err := provider.Exchange(ctx, code, "verifier", "other nonce"); !errors.Is(err, oidc.ErrNonceMismatch) {
+			t.Errorf("Expected nonce mismatch. Got %v", err)
+		}
+	})
+}
@@ -5,43 +5,58 @@ import (
 	"time"
 )
 
+// FixedWindowLimiter allows limit requests per client in consecutive windows.
+// It's cheap but lets up to twice the limit through around a window edge.
 type FixedWindowLimiter struct {
-	sync.RWMutex
-	clients map[string]int
+	sync.Mutex
+	sweeper
+	clients map[string]*fixedWindow
 	limit   int
 	window  time.Duration
 }
 
+type fixedWindow struct {
+	start time.Time
+	count int
+}
+
 func NewFixedWindowLimiter(limit int, window time.Duration) *FixedWindowLimiter {
 	return &FixedWindowLimiter{
-		clients: make(map[string]int),
+		sweeper: newSweeper(window),
+		clients: make(map[string]*fixedWindow),
 		limit:   limit,
 		window:  window,
 	}
 }
 
-func (rl *FixedWindowLimiter) Allow(ip string) (bool, time.Duration) {
-	rl.RLock()
-	count, exists := rl.clients[ip]
-	rl.RUnlock()
-
-	if !exists || count < rl.limit {
-		rl.Lock()
-		if !exists {
-			go rl.resetCount(ip)
+func (rl *FixedWindowLimiter) Allow(key string) Result {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	if rl.due(now) {
+		for client, w := range rl.clients {
+			if now.Sub(w.start) >= rl.window {
+				delete(rl.clients, client)
+			}
 		}
+	}
 
-		rl.clients[ip]++
-		rl.Unlock()
-		return true, 0
+	w, exists := rl.clients[key]
+	if !exists || now.Sub(w.start) >= rl.window {
+		w = &fixedWindow{start: now}
+		rl.clients[key] = w
 	}
 
-	return false, rl.window
-}
+	res := Result{Limit: rl.limit, Reset: w.start.Add(rl.window).Sub(now)}
 
-func (rl *FixedWindowLimiter) resetCount(ip string) {
-	time.Sleep(rl.window)
-	rl.Lock()
-	delete(rl.clients, ip)
-	rl.Unlock()
+	if w.count < rl.limit {
+		w.count++
+		res.Allowed = true
+		res.Remaining = rl.limit - w.count
+		return res
+	}
+
+	res.RetryAfter = res.Reset
+	return res
 }
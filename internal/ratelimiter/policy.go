@@ -0,0 +1,159 @@
+package ratelimiter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// KeyByIP limits every IP address on its own. Forwarded addresses are
+	// only used for requests from trusted proxies.
+	KeyByIP = "ip"
+	// KeyByClient limits authenticated users and API keys on their own,
+	// anonymous requests by IP address.
+	KeyByClient = "client"
+)
+
+const DefaultPolicy = "default"
+
+// Policy is a quota applied to a group of routes.
+type Policy struct {
+	Requests int      `json:"requests"`
+	Window   Duration `json:"window"`
+	// Strategy overrides the strategy of the base Config
+	Strategy string `json:"strategy"`
+	Key      string `json:"key"`
+}
+
+// Route applies a policy to the requests whose path is Path or below it,
+// optionally only for one method.
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Policy string `json:"policy"`
+}
+
+// Policies is the rate limit config of the API. Requests not matching any
+// route fall under DefaultPolicy.
+type Policies struct {
+	Policies map[string]Policy `json:"policies"`
+	Routes   []Route           `json:"routes"`
+}
+
+// Duration is a time.Duration written as a string, e.g. "1m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// DefaultPolicies applies the quota of cfg to every client.
+func DefaultPolicies(cfg Config) *Policies {
+	return &Policies{
+		Policies: map[string]Policy{
+			DefaultPolicy: {
+				Requests: cfg.RequestsPerTimeFrame,
+				Window:   Duration(cfg.TimeFrame),
+				Key:      KeyByClient,
+			},
+		},
+	}
+}
+
+// LoadPolicies reads the JSON policies file at path.
+func LoadPolicies(path string) (*Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policies
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("rate limit policies %s: %w", path, err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("rate limit policies %s: %w", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *Policies) validate() error {
+	if _, ok := p.Policies[DefaultPolicy]; !ok {
+		return fmt.Errorf("the %q policy is missing", DefaultPolicy)
+	}
+
+	for name, policy := range p.Policies {
+		if policy.Requests < 1 || policy.Window <= 0 {
+			return fmt.Errorf("policy %q needs positive requests and window", name)
+		}
+
+		switch policy.Key {
+		case KeyByIP, KeyByClient:
+		default:
+			return fmt.Errorf("policy %q has unknown key %q", name, policy.Key)
+		}
+	}
+
+	for _, route := range p.Routes {
+		if _, ok := p.Policies[route.Policy]; !ok {
+			return fmt.Errorf("route %s uses unknown policy %q", route.Path, route.Policy)
+		}
+	}
+
+	return nil
+}
+
+// Match returns the name of the policy of a request, the route with the
+// longest matching path wins.
+func (p *Policies) Match(method, path string) string {
+	name, longest := DefaultPolicy, -1
+
+	for _, route := range p.Routes {
+		if route.Method != "" && route.Method != method {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(route.Path, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+
+		if len(prefix) > longest {
+			name, longest = route.Policy, len(prefix)
+		}
+	}
+
+	return name
+}
+
+// Config returns the limiter config of the named policy, anything the policy
+// doesn't set is taken from base.
+func (p *Policies) Config(base Config, name string) Config {
+	policy := p.Policies[name]
+
+	cfg := base
+	cfg.Name = name
+	cfg.RequestsPerTimeFrame = policy.Requests
+	cfg.TimeFrame = time.Duration(policy.Window)
+	if policy.Strategy != "" {
+		cfg.Strategy = policy.Strategy
+	}
+
+	return cfg
+}
@@ -0,0 +1,70 @@
+package ratelimiter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicies(t *testing.T) {
+	load := func(t *testing.T, data string) (*Policies, error) {
+		path := filepath.Join(t.TempDir(), "ratelimits.json")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadPolicies(path)
+	}
+
+	t.Run("should match the longest route", func(t *testing.T) {
+		p, err := load(t, `{
+			"policies": {
+				"default": {"requests": 100, "window": "1m", "key": "client"},
+				"auth": {"requests": 5, "window": "1m", "key": "ip"},
+				"login": {"requests": 3, "window": "5m", "key": "ip", "strategy": "sliding-window"}
+			},
+			"routes": [
+				{"path": "/v1/authentication", "policy": "auth"},
+				{"method": "POST", "path": "/v1/authentication/token", "policy": "login"}
+			]
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			method, path, policy string
+		}{
+			{"POST", "/v1/authentication/token", "login"},
+			{"GET", "/v1/authentication/token", "auth"},
+			{"POST", "/v1/authentication/user", "auth"},
+			{"POST", "/v1/authenticationx", DefaultPolicy},
+			{"GET", "/v1/posts/1", DefaultPolicy},
+		}
+
+		for _, tt := range tests {
+			if got := p.Match(tt.method, tt.path); got != tt.policy {
+				t.Errorf("%s %s: expected policy %q. Got %q", tt.method, tt.path, tt.policy, got)
+			}
+		}
+
+		cfg := p.Config(Config{Strategy: TokenBucket, Enabled: true}, "login")
+		if cfg.RequestsPerTimeFrame != 3 || cfg.TimeFrame != 5*time.Minute || cfg.Strategy != SlidingWindow || cfg.Name != "login" || !cfg.Enabled {
+			t.Errorf("Unexpected config %+v", cfg)
+		}
+	})
+
+	t.Run("should reject invalid policies", func(t *testing.T) {
+		for _, data := range []string{
+			`{"policies": {"auth": {"requests": 5, "window": "1m", "key": "ip"}}}`,
+			`{"policies": {"default": {"requests": 0, "window": "1m", "key": "ip"}}}`,
+			`{"policies": {"default": {"requests": 5, "window": "1m", "key": "session"}}}`,
+			`{"policies": {"default": {"requests": 5, "window": "soon", "key": "ip"}}}`,
+			`{"policies": {"default": {"requests": 5, "window": "1m", "key": "ip"}}, "routes": [{"path": "/v1", "policy": "auth"}]}`,
+		} {
+			if _, err := load(t, data); err == nil {
+				t.Errorf("Expected an error for %s", data)
+			}
+		}
+	})
+}
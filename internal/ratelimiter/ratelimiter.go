@@ -1,13 +1,85 @@
 package ratelimiter
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Limiter interface {
-	Allow(ip string) (bool, time.Duration)
+	// Allow counts a request of the client identified by key.
+	Allow(key string) Result
 }
 
+// Result is the outcome of Allow and the state of the client's quota, as
+// reported by the RateLimit-* headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the full quota is available again
+	Reset time.Duration
+	// RetryAfter is how long a rejected client has to wait
+	RetryAfter time.Duration
+}
+
+const (
+	FixedWindow   = "fixed-window"
+	SlidingWindow = "sliding-window"
+	TokenBucket   = "token-bucket"
+)
+
 type Config struct {
 	RequestsPerTimeFrame int
 	TimeFrame            time.Duration
 	Enabled              bool
+	// Strategy is one of FixedWindow, SlidingWindow or TokenBucket, the
+	// fixed window is used if it's empty
+	Strategy string
+	// Redis shares the limits between replicas, see RedisLimiter
+	Redis bool
+	// FailOpen allows requests while Redis is unavailable instead of
+	// rejecting them
+	FailOpen bool
+	// Name keeps the Redis keys of different policies apart
+	Name string
+	// PoliciesFile is the path of the JSON policies, see LoadPolicies
+	PoliciesFile string
+}
+
+// New returns the limiter of the strategy in cfg.
+func New(cfg Config) (Limiter, error) {
+	switch cfg.Strategy {
+	case FixedWindow, "":
+		return NewFixedWindowLimiter(cfg.RequestsPerTimeFrame, cfg.TimeFrame), nil
+	case SlidingWindow:
+		return NewSlidingWindowLimiter(cfg.RequestsPerTimeFrame, cfg.TimeFrame), nil
+	case TokenBucket:
+		return NewTokenBucketLimiter(cfg.RequestsPerTimeFrame, cfg.TimeFrame), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limiter strategy %q", cfg.Strategy)
+	}
+}
+
+// sweeper decides when a limiter drops the state of clients it hasn't seen
+// for a while. Sweeping happens inline on Allow, at most once per interval,
+// so idle clients cost neither memory nor a goroutine.
+type sweeper struct {
+	now       func() time.Time
+	interval  time.Duration
+	lastSweep time.Time
+}
+
+func newSweeper(interval time.Duration) sweeper {
+	return sweeper{now: time.Now, interval: interval}
+}
+
+// due reports whether it's time to sweep again. Callers must hold the
+// limiter's lock.
+func (s *sweeper) due(now time.Time) bool {
+	if now.Sub(s.lastSweep) < s.interval {
+		return false
+	}
+
+	s.lastSweep = now
+	return true
 }
@@ -0,0 +1,172 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+type step struct {
+	advance    time.Duration
+	allow      bool
+	retryAfter time.Duration
+	remaining  int
+}
+
+func checkStep(t *testing.T, i int, s step, res Result) {
+	t.Helper()
+
+	if res.Allowed != s.allow || res.RetryAfter != s.retryAfter || res.Remaining != s.remaining {
+		t.Errorf("step %d: expected (%v, %v, %d remaining). Got (%v, %v, %d remaining)",
+			i, s.allow, s.retryAfter, s.remaining, res.Allowed, res.RetryAfter, res.Remaining)
+	}
+}
+
+func TestLimiters(t *testing.T) {
+	const (
+		limit  = 2
+		window = 10 * time.Second
+	)
+
+	tests := []struct {
+		name     string
+		strategy string
+		steps    []step
+	}{
+		{
+			name:     "fixed window resets after the window",
+			strategy: FixedWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{time.Second, true, 0, 0},
+				{time.Second, false, 8 * time.Second, 0},
+				{8 * time.Second, true, 0, 1},
+			},
+		},
+		{
+			name:     "fixed window allows bursts at the edge",
+			strategy: FixedWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{9 * time.Second, true, 0, 0},
+				{time.Second, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 10 * time.Second, 0},
+			},
+		},
+		{
+			name:     "token bucket refills continuously",
+			strategy: TokenBucket,
+			steps: []step{
+				{0, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+				{4 * time.Second, false, time.Second, 0},
+				{time.Second, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+				{time.Minute, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+			},
+		},
+		{
+			name:     "sliding window weights the previous window",
+			strategy: SlidingWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 15 * time.Second, 0},
+				{14 * time.Second, false, time.Second, 0},
+				{time.Second, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+			},
+		},
+		{
+			name:     "sliding window has no edge bursts",
+			strategy: SlidingWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{9 * time.Second, true, 0, 0},
+				{time.Second, false, 5 * time.Second, 0},
+				{25 * time.Second, true, 0, 1},
+				{0, true, 0, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+			rl := newTestLimiter(t, tt.strategy, limit, window, clock)
+
+			for i, s := range tt.steps {
+				clock.t = clock.t.Add(s.advance)
+
+				checkStep(t, i, s, rl.Allow("127.0.0.1"))
+			}
+		})
+	}
+}
+
+func TestLimitersSweepIdleClients(t *testing.T) {
+	for _, strategy := range []string{FixedWindow, SlidingWindow, TokenBucket} {
+		t.Run(strategy, func(t *testing.T) {
+			clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+			rl := newTestLimiter(t, strategy, 1, time.Second, clock)
+
+			for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+				rl.Allow(ip)
+			}
+
+			clock.t = clock.t.Add(time.Hour)
+			rl.Allow("10.0.0.4")
+
+			if n := clients(rl); n != 1 {
+				t.Errorf("Expected idle clients to be swept. Got %d clients", n)
+			}
+		})
+	}
+}
+
+func TestNewUnknownStrategy(t *testing.T) {
+	if _, err := New(Config{Strategy: "leaky-bucket"}); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func newTestLimiter(t *testing.T, strategy string, limit int, window time.Duration, clock *fakeClock) Limiter {
+	t.Helper()
+
+	rl, err := New(Config{RequestsPerTimeFrame: limit, TimeFrame: window, Strategy: strategy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch rl := rl.(type) {
+	case *FixedWindowLimiter:
+		rl.now = clock.now
+	case *SlidingWindowLimiter:
+		rl.now = clock.now
+	case *TokenBucketLimiter:
+		rl.now = clock.now
+	}
+
+	return rl
+}
+
+func clients(rl Limiter) int {
+	switch rl := rl.(type) {
+	case *FixedWindowLimiter:
+		return len(rl.clients)
+	case *SlidingWindowLimiter:
+		return len(rl.clients)
+	case *TokenBucketLimiter:
+		return len(rl.clients)
+	}
+	return -1
+}
@@ -0,0 +1,161 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisKeyPrefix = "ratelimit"
+
+// The scripts take the limit and the window in milliseconds and return
+// whether the request is allowed, the retry delay, the remaining requests and
+// the reset delay, delays in milliseconds. Time comes from Redis so every
+// replica sees the same clock.
+var (
+	fixedWindowScript = redis.NewScript(`
+		local limit = tonumber(ARGV[1])
+		local count = redis.call('INCR', KEYS[1])
+		if count == 1 then
+			redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		end
+
+		local reset = redis.call('PTTL', KEYS[1])
+		if count <= limit then
+			return {1, 0, limit - count, reset}
+		end
+		return {0, reset, 0, reset}
+	`)
+
+	slidingWindowScript = redis.NewScript(`
+		local limit = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+		local index = math.floor(now / window)
+		local currKey = KEYS[1] .. ':' .. index
+		local curr = tonumber(redis.call('GET', currKey) or '0')
+		local prev = tonumber(redis.call('GET', KEYS[1] .. ':' .. (index - 1)) or '0')
+		local elapsed = now - index * window
+		local weighted = prev * (1 - elapsed / window)
+
+		local allowed, retry, remaining = 0, 0, 0
+		if weighted + curr + 1 <= limit then
+			curr = redis.call('INCR', currKey)
+			redis.call('PEXPIRE', currKey, 2 * window)
+			allowed = 1
+			remaining = math.floor(limit - weighted - curr)
+		elseif curr <= limit - 1 then
+			retry = math.ceil(window * (1 - (limit - 1 - curr) / prev)) - elapsed
+		else
+			retry = window - elapsed + math.ceil(window * (1 - (limit - 1) / curr))
+		end
+
+		local reset = 0
+		if curr > 0 then
+			reset = 2 * window - elapsed
+		elseif prev > 0 then
+			reset = window - elapsed
+		end
+		return {allowed, retry, remaining, reset}
+	`)
+
+	tokenBucketScript = redis.NewScript(`
+		local limit = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		local rate = limit / window
+
+		local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+		local tokens = tonumber(bucket[1]) or limit
+		local last = tonumber(bucket[2]) or now
+		tokens = math.min(limit, tokens + (now - last) * rate)
+
+		local allowed, retry = 0, 0
+		if tokens >= 1 then
+			tokens = tokens - 1
+			allowed = 1
+		else
+			retry = math.ceil((1 - tokens) / rate)
+		end
+
+		redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+		redis.call('PEXPIRE', KEYS[1], window)
+		return {allowed, retry, math.floor(tokens), math.ceil((limit - tokens) / rate)}
+	`)
+)
+
+// RedisLimiter shares the limits of Config between all replicas of the API.
+// If Redis can't be reached, requests are let through when the config says
+// to fail open and rejected otherwise.
+type RedisLimiter struct {
+	rdb      *redis.Client
+	script   *redis.Script
+	name     string
+	limit    int
+	window   time.Duration
+	failOpen bool
+	timeout  time.Duration
+	// OnError is called with the errors of Redis, e.g. to log them
+	OnError func(error)
+}
+
+func NewRedisLimiter(rdb *redis.Client, cfg Config) (*RedisLimiter, error) {
+	var script *redis.Script
+
+	switch cfg.Strategy {
+	case FixedWindow, "":
+		script = fixedWindowScript
+	case SlidingWindow:
+		script = slidingWindowScript
+	case TokenBucket:
+		script = tokenBucketScript
+	default:
+		return nil, fmt.Errorf("unknown rate limiter strategy %q", cfg.Strategy)
+	}
+
+	return &RedisLimiter{
+		rdb:      rdb,
+		script:   script,
+		name:     cfg.Name,
+		limit:    cfg.RequestsPerTimeFrame,
+		window:   cfg.TimeFrame,
+		failOpen: cfg.FailOpen,
+		timeout:  100 * time.Millisecond,
+	}, nil
+}
+
+func (rl *RedisLimiter) Allow(key string) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), rl.timeout)
+	defer cancel()
+
+	// the hash tag keeps all keys of a client on the same cluster slot
+	redisKey := fmt.Sprintf("%s:%s:{%s}", redisKeyPrefix, rl.name, key)
+
+	res, err := rl.script.Run(ctx, rl.rdb, []string{redisKey}, rl.limit, rl.window.Milliseconds()).Int64Slice()
+	if err == nil && len(res) != 4 {
+		err = fmt.Errorf("unexpected rate limiter script result %v", res)
+	}
+	if err != nil {
+		if rl.OnError != nil {
+			rl.OnError(err)
+		}
+
+		if rl.failOpen {
+			return Result{Allowed: true, Limit: rl.limit, Remaining: rl.limit}
+		}
+		return Result{Limit: rl.limit, Reset: rl.window, RetryAfter: rl.window}
+	}
+
+	return Result{
+		Allowed:    res[0] == 1,
+		Limit:      rl.limit,
+		Remaining:  int(res[2]),
+		Reset:      time.Duration(res[3]) * time.Millisecond,
+		RetryAfter: time.Duration(res[1]) * time.Millisecond,
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	const (
+		limit  = 2
+		window = 10 * time.Second
+	)
+
+	tests := []struct {
+		strategy string
+		steps    []step
+	}{
+		{
+			strategy: FixedWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{time.Second, true, 0, 0},
+				{time.Second, false, 8 * time.Second, 0},
+				{8 * time.Second, true, 0, 1},
+			},
+		},
+		{
+			strategy: SlidingWindow,
+			steps: []step{
+				{0, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 15 * time.Second, 0},
+				{14 * time.Second, false, time.Second, 0},
+				{time.Second, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+			},
+		},
+		{
+			strategy: TokenBucket,
+			steps: []step{
+				{0, true, 0, 1},
+				{0, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+				{4 * time.Second, false, time.Second, 0},
+				{time.Second, true, 0, 0},
+				{0, false, 5 * time.Second, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer rdb.Close()
+
+			rl, err := NewRedisLimiter(rdb, Config{RequestsPerTimeFrame: limit, TimeFrame: window, Strategy: tt.strategy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// windows of the sliding window start at multiples of its length
+			now := time.Unix(1_700_000_000, 0)
+			mr.SetTime(now)
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				mr.SetTime(now)
+				mr.FastForward(s.advance)
+
+				checkStep(t, i, s, rl.Allow("127.0.0.1"))
+			}
+
+			// other clients have their own quota
+			if res := rl.Allow("127.0.0.2"); !res.Allowed {
+				t.Error("Expected another client to be allowed")
+			}
+		})
+	}
+}
+
+func TestRedisLimiterUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer rdb.Close()
+
+	mr.Close()
+
+	for _, failOpen := range []bool{true, false} {
+		rl, err := NewRedisLimiter(rdb, Config{RequestsPerTimeFrame: 1, TimeFrame: time.Second, FailOpen: failOpen})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var errs int
+		rl.OnError = func(error) { errs++ }
+
+		if res := rl.Allow("127.0.0.1"); res.Allowed != failOpen {
+			t.Errorf("Expected allow to be %v when failing open is %v", failOpen, failOpen)
+		}
+
+		if errs != 1 {
+			t.Errorf("Expected the error to be reported once. Got %d", errs)
+		}
+	}
+}
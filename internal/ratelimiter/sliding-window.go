@@ -0,0 +1,104 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLimiter approximates a window sliding with every request by
+// weighting the count of the previous fixed window with how much of it still
+// overlaps. It has no edge bursts and only keeps two counters per client.
+type SlidingWindowLimiter struct {
+	sync.Mutex
+	sweeper
+	clients map[string]*slidingWindow
+	limit   int
+	window  time.Duration
+}
+
+type slidingWindow struct {
+	start time.Time
+	prev  int
+	curr  int
+}
+
+func NewSlidingWindowLimiter(limit int, window time.Duration) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		sweeper: newSweeper(window),
+		clients: make(map[string]*slidingWindow),
+		limit:   limit,
+		window:  window,
+	}
+}
+
+func (rl *SlidingWindowLimiter) Allow(key string) Result {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	if rl.due(now) {
+		// after two windows nothing of a client's requests counts anymore
+		for client, w := range rl.clients {
+			if now.Sub(w.start) >= 2*rl.window {
+				delete(rl.clients, client)
+			}
+		}
+	}
+
+	w, exists := rl.clients[key]
+	if !exists {
+		w = &slidingWindow{start: now}
+		rl.clients[key] = w
+	}
+
+	if elapsed := now.Sub(w.start); elapsed >= rl.window {
+		w.prev = w.curr
+		if elapsed >= 2*rl.window {
+			w.prev = 0
+		}
+		w.curr = 0
+		w.start = w.start.Add(elapsed.Truncate(rl.window))
+	}
+
+	elapsed := now.Sub(w.start)
+	weighted := float64(w.prev) * (1 - float64(elapsed)/float64(rl.window))
+
+	res := Result{Limit: rl.limit}
+
+	// count the request itself, so the weighted count never exceeds the limit
+	if weighted+float64(w.curr+1) <= float64(rl.limit) {
+		w.curr++
+		res.Allowed = true
+		res.Remaining = int(float64(rl.limit) - weighted - float64(w.curr))
+	} else {
+		res.RetryAfter = rl.retryAfter(w, elapsed)
+	}
+
+	// requests of the current window still count during the next one
+	switch {
+	case w.curr > 0:
+		res.Reset = 2*rl.window - elapsed
+	case w.prev > 0:
+		res.Reset = rl.window - elapsed
+	}
+
+	return res
+}
+
+// retryAfter returns how long until the weighted count of w leaves room for
+// another request, assuming the client doesn't make any more requests.
+func (rl *SlidingWindowLimiter) retryAfter(w *slidingWindow, elapsed time.Duration) time.Duration {
+	free := float64(rl.limit - 1)
+	window := float64(rl.window)
+
+	if w.curr <= rl.limit-1 {
+		// the previous window has to slide out far enough
+		t := window * (1 - (free-float64(w.curr))/float64(w.prev))
+		return time.Duration(math.Ceil(t)) - elapsed
+	}
+
+	// the current window has to end and then slide out far enough
+	t := window * (1 - free/float64(w.curr))
+	return rl.window - elapsed + time.Duration(math.Ceil(t))
+}
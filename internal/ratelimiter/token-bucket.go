@@ -0,0 +1,81 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucketLimiter gives every client a bucket of limit tokens that refills
+// continuously over window. Bursts up to limit are allowed, after that
+// requests are spread evenly.
+type TokenBucketLimiter struct {
+	sync.Mutex
+	sweeper
+	clients map[string]*bucket
+	limit   int
+	window  time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucketLimiter(limit int, window time.Duration) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		sweeper: newSweeper(window),
+		clients: make(map[string]*bucket),
+		limit:   limit,
+		window:  window,
+	}
+}
+
+func (rl *TokenBucketLimiter) Allow(key string) Result {
+	rl.Lock()
+	defer rl.Unlock()
+
+	now := rl.now()
+	if rl.due(now) {
+		// a bucket untouched for a whole window is full again, which is the
+		// same as not having one
+		for client, b := range rl.clients {
+			if now.Sub(b.last) >= rl.window {
+				delete(rl.clients, client)
+			}
+		}
+	}
+
+	b, exists := rl.clients[key]
+	if !exists {
+		b = &bucket{tokens: float64(rl.limit), last: now}
+		rl.clients[key] = b
+	}
+
+	b.tokens = math.Min(float64(rl.limit), b.tokens+now.Sub(b.last).Seconds()*rl.rate())
+	b.last = now
+
+	res := Result{Limit: rl.limit}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = rl.refillTime(1 - b.tokens)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = rl.refillTime(float64(rl.limit) - b.tokens)
+
+	return res
+}
+
+// refillTime returns how long it takes to refill n tokens.
+func (rl *TokenBucketLimiter) refillTime(n float64) time.Duration {
+	return time.Duration(math.Ceil(n / rl.rate() * float64(time.Second)))
+}
+
+// rate returns the refill rate in tokens per second.
+func (rl *TokenBucketLimiter) rate() float64 {
+	return float64(rl.limit) / rl.window.Seconds()
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey is a long-lived credential for machine clients. Only a hash of the
+// key is stored; the prefix is kept in the clear so users can tell their
+// keys apart.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  string     `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyStore struct {
+	db *sql.DB
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, key *APIKey, plainKey string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		hashToken(plainKey),
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (s *APIKeyStore) GetByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+
+	for rows.Next() {
+		var k APIKey
+		err := rows.Scan(
+			&k.ID,
+			&k.UserID,
+			&k.Name,
+			&k.Prefix,
+			pq.Array(&k.Scopes),
+			&k.CreatedAt,
+			&k.LastUsedAt,
+			&k.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetByKey looks up an unexpired key by its plain value.
+func (s *APIKeyStore) GetByKey(ctx context.Context, plainKey string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+		FROM api_keys
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	k := &APIKey{}
+	err := s.db.QueryRowContext(ctx, query, hashToken(plainKey)).Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Scopes),
+		&k.CreatedAt,
+		&k.LastUsedAt,
+		&k.ExpiresAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// Touch records that a key was used. To spare a write on every request the
+// timestamp is only moved forward once a minute.
+func (s *APIKeyStore) Touch(ctx context.Context, keyID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+
+	_, err := s.db.ExecContext(ctx, query, keyID)
+	return err
+}
+
+func (s *APIKeyStore) Delete(ctx context.Context, userID, keyID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM api_keys WHERE id = $1 AND user_id = $2
+	`
+
+	res, err := s.db.ExecContext(ctx, query, keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
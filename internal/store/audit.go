@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	AuditLoginSucceeded = "login_succeeded"
+	AuditLoginFailed    = "login_failed"
+	AuditLoginLocked    = "login_locked"
+	AuditAccountLocked  = "account_locked"
+	AuditMFAPending     = "mfa_pending"
+	AuditMFAFailed      = "mfa_failed"
+	AuditMFAEnabled     = "mfa_enabled"
+	AuditMFADisabled    = "mfa_disabled"
+	AuditLoginBanned    = "login_banned"
+	AuditRoleChanged    = "role_changed"
+	AuditUserBanned     = "user_banned"
+	AuditUserUnbanned   = "user_unbanned"
+	AuditForcedLogout   = "forced_logout"
+	AuditDeactivated    = "user_deactivated"
+	AuditReactivated    = "user_reactivated"
+)
+
+// AuditEntry records a security relevant event. UserID is nil when the event
+// can't be tied to an account, e.g. a login for an unknown email. ActorID is
+// the signed in user who caused it, e.g. the admin banning UserID; it is nil
+// for anonymous requests like logins.
+type AuditEntry struct {
+	UserID  *int64
+	ActorID *int64
+	Email   string
+	Event   string
+	IP      string
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+func (s *AuditStore) Log(ctx context.Context, entry *AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO auth_audit_log (user_id, actor_id, email, event, ip)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, entry.UserID, entry.ActorID, entry.Email, entry.Event, entry.IP)
+	return err
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// BlockStore keeps track of blocked and muted users. A block hides both
+// users from each other and prevents them from following one another; a
+// mute only hides the muted user's posts from the muter's feed.
+type BlockStore struct {
+	db *sql.DB
+}
+
+// notBlockedSQL is true when no block exists in either direction between the
+// users referenced by the two given SQL expressions.
+func notBlockedSQL(a, b string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM user_blocks ub
+		WHERE (ub.blocker_id = ` + a + ` AND ub.blocked_id = ` + b + `) OR
+			(ub.blocker_id = ` + b + ` AND ub.blocked_id = ` + a + `)
+	)`
+}
+
+// userNotFound maps the foreign key violation of a relation to a user that
+// doesn't exist to ErrNotFound.
+func userNotFound(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Block blocks userID on behalf of blockerID and drops any follow
+// relationship between the two, pending follow requests included. It fails
+// with ErrNotFound when userID doesn't exist.
+func (s *BlockStore) Block(ctx context.Context, blockerID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO user_blocks (blocker_id, blocked_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`
+
+		if _, err := tx.ExecContext(ctx, query, blockerID, userID); err != nil {
+			return userNotFound(err)
+		}
+
+		query = `
+			DELETE FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+		`
+
+		if _, err := tx.ExecContext(ctx, query, blockerID, userID); err != nil {
+			return err
+		}
+
+		query = `
+			DELETE FROM follow_requests
+			WHERE (user_id = $1 AND requester_id = $2) OR (user_id = $2 AND requester_id = $1)
+		`
+
+		_, err := tx.ExecContext(ctx, query, blockerID, userID)
+		return err
+	})
+}
+
+func (s *BlockStore) Unblock(ctx context.Context, blockerID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM user_blocks
+		WHERE blocker_id = $1 AND blocked_id = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, query, blockerID, userID)
+	return err
+}
+
+func (s *BlockStore) Mute(ctx context.Context, muterID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_mutes (muter_id, muted_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query, muterID, userID)
+	return userNotFound(err)
+}
+
+func (s *BlockStore) Unmute(ctx context.Context, muterID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM user_mutes
+		WHERE muter_id = $1 AND muted_id = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, query, muterID, userID)
+	return err
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (s *BlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `SELECT NOT ` + notBlockedSQL("$1", "$2")
+
+	var blocked bool
+	err := s.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type BookmarkedPost struct {
+	Post
+	BookmarkedAt string `json:"bookmarked_at"`
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+func (s *BookmarkStore) Add(ctx context.Context, userID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO bookmarks (user_id, post_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (s *BookmarkStore) Remove(ctx context.Context, userID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM bookmarks
+		WHERE user_id = $1 AND post_id = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+// GetByUserID returns the posts saved by a user, most recently saved first,
+// and the cursor of the next page (nil on the last page). Posts of private
+// accounts the user no longer follows are left out.
+func (s *BookmarkStore) GetByUserID(ctx context.Context, userID int64, q PaginatedQuery) ([]BookmarkedPost, *Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	afterCreatedAt, afterID := keysetAfter(q.After)
+
+	query := `
+		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, p.tags, p.version,
+		u.username, b.created_at
+		FROM bookmarks b
+		JOIN posts p ON p.id = b.post_id
+		LEFT JOIN users u ON u.id = p.user_id
+		WHERE b.user_id = $1 AND
+			(NOT u.is_private OR p.user_id = $1 OR
+				EXISTS (SELECT 1 FROM followers f WHERE f.user_id = p.user_id AND f.follower_id = $1)) AND
+			($3::timestamptz IS NULL OR (b.created_at, b.post_id) < ($3::timestamptz, $4))
+		ORDER BY b.created_at DESC, b.post_id DESC
+		LIMIT $2
+	`
+
+	// one extra row tells us whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Limit+1, afterCreatedAt, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []BookmarkedPost{}
+
+	for rows.Next() {
+		var b BookmarkedPost
+		err := rows.Scan(
+			&b.ID,
+			&b.UserID,
+			&b.Title,
+			&b.Content,
+			&b.CreatedAt,
+			&b.UpdatedAt,
+			pq.Array(&b.Tags),
+			&b.Version,
+			&b.User.Username,
+			&b.BookmarkedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bookmarks = append(bookmarks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	bookmarks, next := nextPage(bookmarks, q.Limit, func(last BookmarkedPost) Cursor {
+		return Cursor{CreatedAt: last.BookmarkedAt, ID: last.ID}
+	})
+
+	return bookmarks, next, nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/Iowel/test-apps/internal/store"
 
@@ -12,9 +13,14 @@ type MockUserStore struct {
 	mock.Mock
 }
 
+type MockTokenStore struct {
+	mock.Mock
+}
+
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:  &MockUserStore{},
+		Tokens: &MockTokenStore{},
 	}
 }
 
@@ -29,3 +35,21 @@ func (m *MockUserStore) Set(ctx context.Context, user *store.User) error {
 
 	return args.Error(0)
 }
+
+func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+
+	return args.Error(0)
+}
+
+func (m *MockTokenStore) Get(ctx context.Context, jti string) (bool, bool, error) {
+	args := m.Called(jti)
+
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTokenStore) Set(ctx context.Context, jti string, revoked bool, expiry time.Time) error {
+	args := m.Called(jti, revoked)
+
+	return args.Error(0)
+}
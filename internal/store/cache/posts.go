@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	PostExpDuration = time.Minute
+	FeedExpDuration = 30 * time.Second
+)
+
+// postStorage is store.Storage.Posts.
+type postStorage interface {
+	Create(context.Context, *store.Post) error
+	Delete(context.Context, int64) error
+	Update(context.Context, *store.Post) error
+	GetByID(context.Context, int64) (*store.Post, error)
+	GetUserFeed(context.Context, int64, store.PaginatedFeedQuery) ([]store.PostWithMetadata, *store.Cursor, error)
+}
+
+// PostStore caches posts and the first page of feeds in front of the
+// Postgres store. Cache keys carry generations instead of being deleted:
+// a load that started before a write then caches its outdated value under a
+// key nobody reads anymore. Every post has a generation of its own. Feeds
+// can't be found one by one when a post changes, so updates and deletes move
+// a global generation; a new post only moves the feed generation of its
+// author, the feeds of their followers show it once they expire.
+// InvalidateFeed moves the feed generation of single users.
+//
+// The write already happened when an invalidation fails, so the error isn't
+// returned but passed to OnError; the entry expires on its own.
+type PostStore struct {
+	postStorage
+	redisDB *redis.Client
+	posts   *readThrough[*store.Post]
+	feeds   *readThrough[feedPage]
+	// OnError is called with the errors of invalidations, e.g. to log them
+	OnError func(error)
+}
+
+type feedPage struct {
+	Posts []store.PostWithMetadata `json:"posts"`
+	Next  *store.Cursor            `json:"next"`
+}
+
+const postsGenKey = "posts-gen"
+
+func NewPostStore(posts postStorage, redisDB *redis.Client) *PostStore {
+	return &PostStore{
+		postStorage: posts,
+		redisDB:     redisDB,
+		posts:       newReadThrough[*store.Post](redisDB, PostExpDuration),
+		feeds:       newReadThrough[feedPage](redisDB, FeedExpDuration),
+	}
+}
+
+func (s *PostStore) GetByID(ctx context.Context, postID int64) (*store.Post, error) {
+	gens, err := s.redisDB.MGet(ctx, postGenKey(postID)).Result()
+	if err != nil {
+		return s.postStorage.GetByID(ctx, postID)
+	}
+
+	return s.posts.get(ctx, postKey(postID, generation(gens[0])), func(ctx context.Context) (*store.Post, error) {
+		return s.postStorage.GetByID(ctx, postID)
+	})
+}
+
+func (s *PostStore) Create(ctx context.Context, post *store.Post) error {
+	if err := s.postStorage.Create(ctx, post); err != nil {
+		return err
+	}
+
+	s.InvalidateFeed(ctx, post.UserID)
+	return nil
+}
+
+func (s *PostStore) Update(ctx context.Context, post *store.Post) error {
+	if err := s.postStorage.Update(ctx, post); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, post.ID)
+	return nil
+}
+
+func (s *PostStore) Delete(ctx context.Context, postID int64) error {
+	if err := s.postStorage.Delete(ctx, postID); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, postID)
+	return nil
+}
+
+func (s *PostStore) invalidate(ctx context.Context, postID int64) {
+	s.report(s.redisDB.Incr(ctx, postGenKey(postID)).Err())
+	s.report(s.redisDB.Incr(ctx, postsGenKey).Err())
+}
+
+// GetUserFeed caches the first page of a feed, which is what most requests
+// ask for; later pages are rarely read twice. New follows show up once the
+// cached page expires.
+func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, *store.Cursor, error) {
+	if fq.After != nil || fq.Offset > 0 {
+		return s.postStorage.GetUserFeed(ctx, userID, fq)
+	}
+
+	key, err := s.feedKey(ctx, userID, fq)
+	if err != nil {
+		return s.postStorage.GetUserFeed(ctx, userID, fq)
+	}
+
+	page, err := s.feeds.get(ctx, key, func(ctx context.Context) (feedPage, error) {
+		posts, next, err := s.postStorage.GetUserFeed(ctx, userID, fq)
+		return feedPage{Posts: posts, Next: next}, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return page.Posts, page.Next, nil
+}
+
+// InvalidateFeed drops the cached feeds of users, e.g. after one of them
+// blocked someone whose posts must disappear right away.
+func (s *PostStore) InvalidateFeed(ctx context.Context, userIDs ...int64) {
+	for _, userID := range userIDs {
+		s.report(s.redisDB.Incr(ctx, feedGenKey(userID)).Err())
+	}
+}
+
+func (s *PostStore) report(err error) {
+	if err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+func (s *PostStore) feedKey(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) (string, error) {
+	gens, err := s.redisDB.MGet(ctx, postsGenKey, feedGenKey(userID)).Result()
+	if err != nil {
+		return "", err
+	}
+
+	query, err := json.Marshal(fq)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(query)
+
+	return fmt.Sprintf("feed-%d-%s-%s-%s", userID, generation(gens[0]), generation(gens[1]), hex.EncodeToString(hash[:8])), nil
+}
+
+// generation formats a generation read with MGET, keys that were never
+// incremented are nil.
+func generation(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return "0"
+}
+
+func postKey(postID int64, gen string) string {
+	return fmt.Sprintf("post-%d-%s", postID, gen)
+}
+
+func postGenKey(postID int64) string {
+	return fmt.Sprintf("post-gen-%d", postID)
+}
+
+func feedGenKey(userID int64) string {
+	return fmt.Sprintf("feed-gen-%d", userID)
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Iowel/test-apps/internal/store"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// countingPosts is a post store counting its reads.
+type countingPosts struct {
+	gets  atomic.Int32
+	feeds atomic.Int32
+	// release holds GetByID until it's closed, if set
+	release chan struct{}
+}
+
+func (p *countingPosts) Create(context.Context, *store.Post) error { return nil }
+func (p *countingPosts) Delete(context.Context, int64) error       { return nil }
+func (p *countingPosts) Update(context.Context, *store.Post) error { return nil }
+
+func (p *countingPosts) GetByID(ctx context.Context, postID int64) (*store.Post, error) {
+	p.gets.Add(1)
+	if p.release != nil {
+		<-p.release
+	}
+	if postID == 404 {
+		return nil, store.ErrNotFound
+	}
+	return &store.Post{ID: postID, Title: "gopher"}, nil
+}
+
+func (p *countingPosts) GetUserFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, *store.Cursor, error) {
+	p.feeds.Add(1)
+	return []store.PostWithMetadata{{Post: store.Post{ID: 1}}}, &store.Cursor{ID: 1}, nil
+}
+
+func newTestPostStore(t *testing.T) (*PostStore, *countingPosts) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	posts := &countingPosts{}
+	return NewPostStore(posts, rdb), posts
+}
+
+func TestPostStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should read posts through the cache until they change", func(t *testing.T) {
+		s, posts := newTestPostStore(t)
+
+		for i := 0; i < 3; i++ {
+			post, err := s.GetByID(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// callers get copies of their own
+			post.Title = "changed"
+		}
+
+		post, err := s.GetByID(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Title != "gopher" {
+			t.Errorf("Expected the cached post to be unchanged. Got %q", post.Title)
+		}
+
+		if err := s.Update(ctx, &store.Post{ID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetByID(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := posts.gets.Load(); n != 2 {
+			t.Errorf("Expected 2 loads. Got %d", n)
+		}
+	})
+
+	t.Run("should not cache missing posts", func(t *testing.T) {
+		s, posts := newTestPostStore(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := s.GetByID(ctx, 404); err != store.ErrNotFound {
+				t.Errorf("Expected ErrNotFound. Got %v", err)
+			}
+		}
+
+		if n := posts.gets.Load(); n != 2 {
+			t.Errorf("Expected 2 loads. Got %d", n)
+		}
+	})
+
+	t.Run("should load a post once for concurrent misses", func(t *testing.T) {
+		s, posts := newTestPostStore(t)
+		posts.release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.GetByID(ctx, 1); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+
+		// let the callers pile up behind the first load
+		time.Sleep(50 * time.Millisecond)
+		close(posts.release)
+		wg.Wait()
+
+		if n := posts.gets.Load(); n != 1 {
+			t.Errorf("Expected 1 load. Got %d", n)
+		}
+	})
+
+	t.Run("should not cache posts loaded before they changed", func(t *testing.T) {
+		s, posts := newTestPostStore(t)
+		posts.release = make(chan struct{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if _, err := s.GetByID(ctx, 1); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// let the load start before the post changes
+		time.Sleep(50 * time.Millisecond)
+		if err := s.Update(ctx, &store.Post{ID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		close(posts.release)
+		<-done
+
+		if _, err := s.GetByID(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := posts.gets.Load(); n != 2 {
+			t.Errorf("Expected the post to be loaded again. Got %d loads", n)
+		}
+	})
+
+	t.Run("should cache first feed pages until posts or the user's feed change", func(t *testing.T) {
+		s, posts := newTestPostStore(t)
+		fq := store.PaginatedFeedQuery{Limit: 20, Sort: "desc"}
+
+		feed := func() {
+			t.Helper()
+			if _, next, err := s.GetUserFeed(ctx, 7, fq); err != nil || next == nil {
+				t.Fatalf("Unexpected feed result: next %v, err %v", next, err)
+			}
+		}
+
+		feed()
+		feed()
+		if n := posts.feeds.Load(); n != 1 {
+			t.Errorf("Expected 1 load. Got %d", n)
+		}
+
+		if err := s.Create(ctx, &store.Post{UserID: 8}); err != nil {
+			t.Fatal(err)
+		}
+		feed()
+		if n := posts.feeds.Load(); n != 1 {
+			t.Errorf("Expected a post of another user to keep the feed. Got %d loads", n)
+		}
+
+		if err := s.Create(ctx, &store.Post{UserID: 7}); err != nil {
+			t.Fatal(err)
+		}
+		feed()
+		if n := posts.feeds.Load(); n != 2 {
+			t.Errorf("Expected a new post to invalidate the feed of its author. Got %d loads", n)
+		}
+
+		if err := s.Update(ctx, &store.Post{ID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		feed()
+		if n := posts.feeds.Load(); n != 3 {
+			t.Errorf("Expected an update to invalidate the feed. Got %d loads", n)
+		}
+
+		s.InvalidateFeed(ctx, 7)
+		feed()
+		if n := posts.feeds.Load(); n != 4 {
+			t.Errorf("Expected InvalidateFeed to invalidate the feed. Got %d loads", n)
+		}
+
+		fq.After = &store.Cursor{ID: 1}
+		feed()
+		feed()
+		if n := posts.feeds.Load(); n != 6 {
+			t.Errorf("Expected later pages not to be cached. Got %d loads", n)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
+)
+
+// readThrough caches the values of a loader in Redis. Concurrent misses of
+// the same key share a single load, so an expired hot key doesn't send every
+// request to Postgres at once. Redis errors fall back to the loader: the
+// cache may make things faster, never unavailable.
+type readThrough[V any] struct {
+	redisDB *redis.Client
+	exp     time.Duration
+	group   singleflight.Group
+}
+
+func newReadThrough[V any](redisDB *redis.Client, exp time.Duration) *readThrough[V] {
+	return &readThrough[V]{redisDB: redisDB, exp: exp}
+}
+
+func (c *readThrough[V]) get(ctx context.Context, key string, load func(context.Context) (V, error)) (V, error) {
+	var value V
+
+	data, err := c.redisDB.Get(ctx, key).Bytes()
+	if err == nil && json.Unmarshal(data, &value) == nil {
+		return value, nil
+	}
+
+	// the shared load hands out the encoded value, every caller decodes a
+	// copy of its own it may modify
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		// one caller giving up must not fail the others
+		ctx := context.WithoutCancel(ctx)
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		c.redisDB.SetEX(ctx, key, data, c.exp)
+
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+
+	err = json.Unmarshal(v.([]byte), &value)
+	return value, err
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+)
+
+// blockStorage is store.Storage.Blocks.
+type blockStorage interface {
+	Block(ctx context.Context, blockerID, userID int64) error
+	Unblock(ctx context.Context, blockerID, userID int64) error
+	Mute(ctx context.Context, muterID, userID int64) error
+	Unmute(ctx context.Context, muterID, userID int64) error
+	IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+}
+
+// BlockStore drops the cached feeds a block or mute changes, so posts of
+// blocked and muted users disappear right away instead of when the cached
+// page expires.
+type BlockStore struct {
+	blockStorage
+	posts *PostStore
+}
+
+func NewBlockStore(blocks blockStorage, posts *PostStore) *BlockStore {
+	return &BlockStore{blockStorage: blocks, posts: posts}
+}
+
+// Block hides the posts of each user from the other.
+func (s *BlockStore) Block(ctx context.Context, blockerID, userID int64) error {
+	if err := s.blockStorage.Block(ctx, blockerID, userID); err != nil {
+		return err
+	}
+
+	s.posts.InvalidateFeed(ctx, blockerID, userID)
+	return nil
+}
+
+func (s *BlockStore) Unblock(ctx context.Context, blockerID, userID int64) error {
+	if err := s.blockStorage.Unblock(ctx, blockerID, userID); err != nil {
+		return err
+	}
+
+	s.posts.InvalidateFeed(ctx, blockerID, userID)
+	return nil
+}
+
+func (s *BlockStore) Mute(ctx context.Context, muterID, userID int64) error {
+	if err := s.blockStorage.Mute(ctx, muterID, userID); err != nil {
+		return err
+	}
+
+	s.posts.InvalidateFeed(ctx, muterID)
+	return nil
+}
+
+func (s *BlockStore) Unmute(ctx context.Context, muterID, userID int64) error {
+	if err := s.blockStorage.Unmute(ctx, muterID, userID); err != nil {
+		return err
+	}
+
+	s.posts.InvalidateFeed(ctx, muterID)
+	return nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/Iowel/test-apps/internal/store"
 
@@ -12,11 +13,29 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, int64) error
 	}
+	Tokens interface {
+		Get(ctx context.Context, jti string) (revoked bool, ok bool, err error)
+		Set(ctx context.Context, jti string, revoked bool, expiry time.Time) error
+	}
+}
+
+// NewCachedStorage puts read-through caches in front of the hot reads of s.
+// onError is called with the errors of cache invalidations.
+func NewCachedStorage(s store.Storage, redisDB *redis.Client, onError func(error)) store.Storage {
+	posts := NewPostStore(s.Posts, redisDB)
+	posts.OnError = onError
+
+	s.Posts = posts
+	s.Blocks = NewBlockStore(s.Blocks, posts)
+
+	return s
 }
 
 func NewRedisStorage(redisDB *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{redisDB: redisDB},
+		Users:  &UserStore{redisDB: redisDB},
+		Tokens: &TokenStore{redisDB: redisDB},
 	}
 }
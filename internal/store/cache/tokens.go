@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TokenStore caches whether access tokens are revoked. Revocations are
+// written through, so a cached "not revoked" is overwritten the moment the
+// token is revoked and both answers can live until the token expires.
+type TokenStore struct {
+	redisDB *redis.Client
+}
+
+// Get returns whether the token with the given jti is revoked and whether
+// the answer was cached at all.
+func (t *TokenStore) Get(ctx context.Context, jti string) (revoked bool, ok bool, err error) {
+	cacheKey := fmt.Sprintf("revoked-%v", jti)
+
+	data, err := t.redisDB.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		return false, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+
+	return data == "1", true, nil
+}
+
+func (t *TokenStore) Set(ctx context.Context, jti string, revoked bool, expiry time.Time) error {
+	cacheKey := fmt.Sprintf("revoked-%v", jti)
+
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return nil
+	}
+
+	value := "0"
+	if revoked {
+		value = "1"
+	}
+
+	return t.redisDB.SetEX(ctx, cacheKey, value, ttl).Err()
+}
@@ -17,6 +17,14 @@ type UserStore struct {
 
 const UserExpDuration = time.Minute
 
+// cachedUser adds the fields AuthTokenMiddleware relies on but that are
+// hidden from the public JSON of a user.
+type cachedUser struct {
+	*store.User
+	Ban               *store.Ban `json:"ban"`
+	SessionsRevokedAt *time.Time `json:"sessions_revoked_at"`
+}
+
 func (u *UserStore) Get(ctx context.Context, userID int64) (*store.User, error) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
 
@@ -27,7 +35,7 @@ func (u *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 		return nil, err
 	}
 
-	var user store.User
+	user := cachedUser{User: &store.User{}}
 	if data != "" {
 		err := json.Unmarshal([]byte(data), &user)
 		if err != nil {
@@ -35,16 +43,25 @@ func (u *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 		}
 	}
 
-	return &user, nil
+	user.User.Ban = user.Ban
+	user.User.SessionsRevokedAt = user.SessionsRevokedAt
+
+	return user.User, nil
 }
 
 func (u *UserStore) Set(ctx context.Context, user *store.User) error {
 	cacheKey := fmt.Sprintf("user-%v", user.ID)
 
-	data, err := json.Marshal(user)
+	data, err := json.Marshal(cachedUser{user, user.Ban, user.SessionsRevokedAt})
 	if err != nil {
 		return err
 	}
 
 	return u.redisDB.SetEX(ctx, cacheKey, data, UserExpDuration).Err()
 }
+
+func (u *UserStore) Delete(ctx context.Context, userID int64) error {
+	cacheKey := fmt.Sprintf("user-%v", userID)
+
+	return u.redisDB.Del(ctx, cacheKey).Err()
+}
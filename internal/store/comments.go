@@ -3,46 +3,222 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID        int64      `json:"id"`
+	PostID    int64      `json:"post_id"`
+	UserID    int64      `json:"user_id"`
+	ParentID  *int64     `json:"parent_id"`
+	Depth     int        `json:"depth"`
+	Content   string     `json:"content"`
+	CreatedAt string     `json:"created_at"`
+	User      User       `json:"user"`
+	Replies   []*Comment `json:"replies,omitempty"`
 }
 
 type CommentStore struct {
 	db *sql.DB
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+// GetByPostID returns one page of comment threads of a post, newest thread
+// first, along with the cursor of the next page (nil on the last page).
+// Pages are cut on top-level comments; every thread carries its replies
+// nested in the order they were written, down to cq.MaxDepth. Comments of
+// users blocking or blocked by cq.ViewerID are left out with their replies.
+func (s *CommentStore) GetByPostID(ctx context.Context, postID int64, cq PaginatedCommentsQuery) ([]Comment, *Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	afterCreatedAt, afterID := keysetAfter(cq.After)
+
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c
+		WITH RECURSIVE page AS (
+			SELECT id, post_id, user_id, parent_id, depth, content, created_at FROM comments
+			WHERE post_id = $1 AND parent_id IS NULL AND
+				($2::timestamptz IS NULL OR (created_at, id) < ($2::timestamptz, $3)) AND
+				` + notBlockedSQL("$6::bigint", "user_id") + `
+			ORDER BY created_at DESC, id DESC
+			LIMIT $4
+		), thread AS (
+			SELECT * FROM page
+			UNION ALL
+			SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at FROM comments c
+			JOIN thread t ON c.parent_id = t.id
+			WHERE c.depth <= $5 AND ` + notBlockedSQL("$6::bigint", "c.user_id") + `
+		)
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at, users.username, users.id FROM thread c
 		join users on users.id = c.user_id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at DESC
+		ORDER BY c.depth, c.created_at, c.id
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, postID)
+	// one extra thread tells us whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, postID, afterCreatedAt, afterID, cq.Limit+1, cq.MaxDepth, cq.ViewerID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	comments := []Comment{}
+	var flat []*Comment
 
 	for rows.Next() {
-		var c Comment
-		c.User = User{}
-		err := rows.Scan(&c.ID, &c.PostID, &c.User.ID, &c.Content, &c.CreatedAt, &c.User.Username, &c.User.ID)
+		c := &Comment{}
+		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.ParentID, &c.Depth, &c.Content, &c.CreatedAt, &c.User.Username, &c.User.ID)
 		if err != nil {
+			return nil, nil, err
+		}
+		flat = append(flat, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	comments := buildCommentTree(flat, cq.MaxDepth)
+
+	comments, next := nextPage(comments, cq.Limit, func(last Comment) Cursor {
+		return Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	})
+
+	return comments, next, nil
+}
+
+// buildCommentTree nests comments under their parents. The input must be
+// ordered by depth so that a parent is always seen before its replies.
+// Replies deeper than maxDepth and replies whose parent is missing, e.g.
+// left out for a block, are dropped along with their own replies.
+func buildCommentTree(flat []*Comment, maxDepth int) []Comment {
+	byID := make(map[int64]*Comment, len(flat))
+	var roots []*Comment
+
+	for _, c := range flat {
+		if c.Depth > maxDepth {
+			continue
+		}
+
+		if c.ParentID == nil {
+			roots = append(roots, c)
+			byID[c.ID] = c
+			continue
+		}
+
+		if parent, ok := byID[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+			byID[c.ID] = c
+		}
+	}
+
+	comments := make([]Comment, 0, len(roots))
+	for i := len(roots) - 1; i >= 0; i-- {
+		comments = append(comments, *roots[i])
+	}
+
+	return comments
+}
+
+func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO comments (post_id, user_id, parent_id, depth, content)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query, comment.PostID, comment.UserID, comment.ParentID, comment.Depth, comment.Content).Scan(
+		&comment.ID,
+		&comment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *CommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at, users.username, users.id FROM comments c
+		join users on users.id = c.user_id
+		WHERE c.id = $1
+	`
+
+	var c Comment
+	err := s.db.QueryRowContext(ctx, query, commentID).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.UserID,
+		&c.ParentID,
+		&c.Depth,
+		&c.Content,
+		&c.CreatedAt,
+		&c.User.Username,
+		&c.User.ID,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
 			return nil, err
 		}
-		comments = append(comments, c)
 	}
 
-	return comments, nil
+	return &c, nil
+}
+
+func (s *CommentStore) Update(ctx context.Context, comment *Comment) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE comments
+		SET content = $1
+		WHERE id = $2
+	`
+
+	res, err := s.db.ExecContext(ctx, query, comment.Content, comment.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *CommentStore) Delete(ctx context.Context, commentID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM comments WHERE id = $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query, commentID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
 }
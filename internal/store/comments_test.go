@@ -0,0 +1,94 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	parent := func(id int64) *int64 { return &id }
+
+	// tree renders the ids of a tree, replies in brackets
+	var tree func(comments []*Comment) []any
+	tree = func(comments []*Comment) []any {
+		out := []any{}
+		for _, c := range comments {
+			out = append(out, c.ID)
+			if len(c.Replies) > 0 {
+				out = append(out, tree(c.Replies))
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		flat     []*Comment
+		maxDepth int
+		want     []any
+	}{
+		{
+			name: "no comments",
+			want: []any{},
+		},
+		{
+			name: "threads newest first, replies in order",
+			flat: []*Comment{
+				{ID: 1},
+				{ID: 2},
+				{ID: 3, ParentID: parent(1), Depth: 1},
+				{ID: 4, ParentID: parent(1), Depth: 1},
+				{ID: 5, ParentID: parent(2), Depth: 1},
+				{ID: 6, ParentID: parent(3), Depth: 2},
+			},
+			maxDepth: 5,
+			want:     []any{int64(2), []any{int64(5)}, int64(1), []any{int64(3), []any{int64(6)}, int64(4)}},
+		},
+		{
+			name: "replies to missing parents are dropped with their replies",
+			flat: []*Comment{
+				{ID: 1},
+				{ID: 2, ParentID: parent(1), Depth: 1},
+				{ID: 3, ParentID: parent(9), Depth: 1},
+				{ID: 4, ParentID: parent(3), Depth: 2},
+				{ID: 5, ParentID: parent(4), Depth: 3},
+			},
+			maxDepth: 5,
+			want:     []any{int64(1), []any{int64(2)}},
+		},
+		{
+			name: "replies deeper than the max depth are dropped",
+			flat: []*Comment{
+				{ID: 1},
+				{ID: 2, ParentID: parent(1), Depth: 1},
+				{ID: 3, ParentID: parent(2), Depth: 2},
+				{ID: 4, ParentID: parent(3), Depth: 3},
+			},
+			maxDepth: 2,
+			want:     []any{int64(1), []any{int64(2), []any{int64(3)}}},
+		},
+		{
+			name: "max depth zero keeps top-level comments only",
+			flat: []*Comment{
+				{ID: 1},
+				{ID: 2, ParentID: parent(1), Depth: 1},
+			},
+			want: []any{int64(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments := buildCommentTree(tt.flat, tt.maxDepth)
+
+			roots := make([]*Comment, len(comments))
+			for i := range comments {
+				roots[i] = &comments[i]
+			}
+
+			if got := tree(roots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected tree %v. Got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// FollowRequestStore holds pending follows of private accounts until the
+// account owner approves or rejects them.
+type FollowRequestStore struct {
+	db *sql.DB
+}
+
+func (s *FollowRequestStore) Create(ctx context.Context, requesterID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO follow_requests (user_id, requester_id)
+		SELECT $1, $2
+		WHERE ` + notBlockedSQL("$1::bigint", "$2::bigint") + ` AND
+			NOT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		// either blocked or already following
+		var blocked bool
+		err := s.db.QueryRowContext(ctx, `SELECT NOT `+notBlockedSQL("$1", "$2"), userID, requesterID).Scan(&blocked)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// GetIncoming returns the pending requests to follow userID, oldest first so
+// that they are answered in the order they came in.
+func (s *FollowRequestStore) GetIncoming(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	afterCreatedAt, afterID := keysetAfter(q.After)
+
+	query := `
+		SELECT u.id, u.username, fr.created_at
+		FROM follow_requests fr
+		JOIN users u ON u.id = fr.requester_id
+		WHERE fr.user_id = $1 AND
+			($3::timestamptz IS NULL OR (fr.created_at, fr.requester_id) > ($3::timestamptz, $4))
+		ORDER BY fr.created_at, fr.requester_id
+		LIMIT $2
+	`
+
+	// one extra row tells us whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Limit+1, afterCreatedAt, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	requests := []FollowedUser{}
+
+	for rows.Next() {
+		var u FollowedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.FollowedAt); err != nil {
+			return nil, nil, err
+		}
+		requests = append(requests, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	requests, next := nextPage(requests, q.Limit, func(last FollowedUser) Cursor {
+		return Cursor{CreatedAt: last.FollowedAt, ID: last.ID}
+	})
+
+	return requests, next, nil
+}
+
+// Approve turns the pending request of requesterID into a follow of userID.
+// Approve turns a pending request into a follow. It fails with ErrNotFound
+// when there is no such request and with ErrBlocked when either user has
+// blocked the other since it was made.
+func (s *FollowRequestStore) Approve(ctx context.Context, userID, requesterID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.delete(ctx, tx, userID, requesterID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO followers (user_id, follower_id)
+			SELECT $1, $2
+			WHERE ` + notBlockedSQL("$1::bigint", "$2::bigint") + `
+			ON CONFLICT DO NOTHING
+		`
+
+		res, err := tx.ExecContext(ctx, query, userID, requesterID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			// either blocked or already following
+			var blocked bool
+			err := tx.QueryRowContext(ctx, `SELECT NOT `+notBlockedSQL("$1", "$2"), userID, requesterID).Scan(&blocked)
+			if err != nil {
+				return err
+			}
+			if blocked {
+				return ErrBlocked
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *FollowRequestStore) Reject(ctx context.Context, userID, requesterID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return s.delete(ctx, tx, userID, requesterID)
+	})
+}
+
+func (s *FollowRequestStore) delete(ctx context.Context, tx *sql.Tx, userID, requesterID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM follow_requests
+		WHERE user_id = $1 AND requester_id = $2
+	`
+
+	res, err := tx.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
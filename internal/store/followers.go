@@ -13,10 +13,19 @@ type Follower struct {
 	CreatedAt  string `json:"created_at"`
 }
 
+// FollowedUser is one side of a follow relationship in a follower listing.
+type FollowedUser struct {
+	ID         int64  `json:"id"`
+	Username   string `json:"username"`
+	FollowedAt string `json:"followed_at"`
+}
+
 type FollowerStore struct {
 	db *sql.DB
 }
 
+// Follow makes followerID follow userID. It fails with ErrBlocked when
+// either of them has blocked the other.
 func (f *FollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -24,16 +33,28 @@ func (f *FollowerStore) Follow(ctx context.Context, followerID, userID int64) er
 	query := `
 	INSERT into followers
 	(user_id, follower_id)
-	values ($1, $2)
+	SELECT $1, $2
+	WHERE ` + notBlockedSQL("$1::bigint", "$2::bigint") + `
 	`
 
-	_, err := f.db.ExecContext(ctx, query, userID, followerID)
+	res, err := f.db.ExecContext(ctx, query, userID, followerID)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			return ErrConflict
 		}
+		return err
 	}
-	return err
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrBlocked
+	}
+
+	return nil
 }
 
 func (f *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
@@ -48,3 +69,96 @@ func (f *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64)
 	_, err := f.db.ExecContext(ctx, query, userID, followerID)
 	return err
 }
+
+// GetFollowers returns the users following userID, most recent first.
+func (f *FollowerStore) GetFollowers(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1 AND
+			($3::timestamptz IS NULL OR (f.created_at, f.follower_id) < ($3::timestamptz, $4))
+		ORDER BY f.created_at DESC, f.follower_id DESC
+		LIMIT $2
+	`
+
+	return f.list(ctx, query, userID, q)
+}
+
+// GetFollowing returns the users followed by userID, most recent first.
+func (f *FollowerStore) GetFollowing(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1 AND
+			($3::timestamptz IS NULL OR (f.created_at, f.user_id) < ($3::timestamptz, $4))
+		ORDER BY f.created_at DESC, f.user_id DESC
+		LIMIT $2
+	`
+
+	return f.list(ctx, query, userID, q)
+}
+
+func (f *FollowerStore) list(ctx context.Context, query string, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	afterCreatedAt, afterID := keysetAfter(q.After)
+
+	// one extra row tells us whether there is a next page
+	rows, err := f.db.QueryContext(ctx, query, userID, q.Limit+1, afterCreatedAt, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := []FollowedUser{}
+
+	for rows.Next() {
+		var u FollowedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.FollowedAt); err != nil {
+			return nil, nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	users, next := nextPage(users, q.Limit, func(last FollowedUser) Cursor {
+		return Cursor{CreatedAt: last.FollowedAt, ID: last.ID}
+	})
+
+	return users, next, nil
+}
+
+// Counts returns how many users follow userID and how many userID follows.
+func (f *FollowerStore) Counts(ctx context.Context, userID int64) (followers int, following int, err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT count(*) FROM followers WHERE user_id = $1),
+			(SELECT count(*) FROM followers WHERE follower_id = $1)
+	`
+
+	err = f.db.QueryRowContext(ctx, query, userID).Scan(&followers, &following)
+	return followers, following, err
+}
+
+func (f *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2
+		)
+	`
+
+	var following bool
+	err := f.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+	return following, err
+}
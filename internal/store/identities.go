@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// IdentityStore links accounts of external identity providers to users.
+// An identity is keyed by the provider (its issuer) and the subject the
+// provider knows the user by.
+type IdentityStore struct {
+	db *sql.DB
+}
+
+func (s *IdentityStore) GetUser(ctx context.Context, provider, subject string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT u.id, u.username, u.email, u.created_at, u.totp_enabled,
+		u.banned_at, u.banned_until, coalesce(u.ban_reason, '')
+		FROM user_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2 AND u.is_active = true
+	`
+
+	var ban banColumns
+
+	user := &User{}
+	err := s.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+		&user.TOTPEnabled,
+		&ban.at,
+		&ban.until,
+		&ban.reason,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	user.Ban = ban.ban()
+
+	return user, nil
+}
+
+func (s *IdentityStore) Link(ctx context.Context, userID int64, provider, subject string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_identities (provider, subject, user_id)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, provider, subject, userID)
+	return err
+}
+
+// CreateUser creates an already active user for an external identity; the
+// provider has verified the email, so there is no invitation.
+func (s *IdentityStore) CreateUser(ctx context.Context, user *User, provider, subject string) error {
+	users := &UserStore{s.db}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := users.Create(ctx, tx, user); err != nil {
+			return err
+		}
+
+		user.IsActive = true
+		if err := users.update(ctx, tx, user); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO user_identities (provider, subject, user_id)
+			VALUES ($1, $2, $3)
+		`
+
+		_, err := tx.ExecContext(ctx, query, provider, subject, user.ID)
+		return err
+	})
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TOTP is the two-factor enrollment of a user. The secret is stored
+// encrypted, the store never sees it in the clear.
+type TOTP struct {
+	Secret  []byte
+	Enabled bool
+}
+
+type MFAStore struct {
+	db *sql.DB
+}
+
+// Enroll stores a new (not yet enabled) TOTP secret and recovery codes,
+// replacing any previous enrollment. Recovery codes are stored hashed.
+func (s *MFAStore) Enroll(ctx context.Context, userID int64, secret []byte, recoveryCodes []string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users SET totp_secret = $1, totp_enabled = false, totp_last_step = 0
+			WHERE id = $2
+		`
+
+		if _, err := tx.ExecContext(ctx, query, secret, userID); err != nil {
+			return err
+		}
+
+		if err := deleteRecoveryCodes(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`
+
+		for _, code := range recoveryCodes {
+			if _, err := tx.ExecContext(ctx, query, userID, hashToken(code)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *MFAStore) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT totp_secret, totp_enabled FROM users
+		WHERE id = $1 AND totp_secret IS NOT NULL
+	`
+
+	totp := &TOTP{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&totp.Secret, &totp.Enabled)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return totp, nil
+}
+
+func (s *MFAStore) Enable(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users SET totp_enabled = true
+		WHERE id = $1 AND totp_secret IS NOT NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MFAStore) Disable(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0
+			WHERE id = $1
+		`
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		return deleteRecoveryCodes(ctx, tx, userID)
+	})
+}
+
+// UseStep records the time step of an accepted code. It reports false if
+// that step (or a later one) was already used, i.e. the code is replayed.
+func (s *MFAStore) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND totp_last_step < $2
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	return rows == 1, err
+}
+
+// UseRecoveryCode consumes a recovery code, reporting false if it doesn't
+// exist or was already used.
+func (s *MFAStore) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	res, err := s.db.ExecContext(ctx, query, userID, hashToken(code))
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	return rows == 1, err
+}
+
+func deleteRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM mfa_recovery_codes WHERE user_id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
@@ -12,9 +12,74 @@ type MockUserStore struct {
 	mock.Mock
 }
 
+type MockCommentStore struct {
+	mock.Mock
+}
+
+type MockPostStore struct {
+	mock.Mock
+}
+
+type MockReactionStore struct {
+	mock.Mock
+}
+
+type MockBookmarkStore struct {
+	mock.Mock
+}
+
+type MockFollowerStore struct {
+	mock.Mock
+}
+
+type MockBlockStore struct {
+	mock.Mock
+}
+
+type MockFollowRequestStore struct {
+	mock.Mock
+}
+
+type MockTokenStore struct {
+	mock.Mock
+}
+
+type MockAuditStore struct {
+	mock.Mock
+}
+
+type MockMFAStore struct {
+	mock.Mock
+}
+
+type MockAPIKeyStore struct {
+	mock.Mock
+}
+
+type MockIdentityStore struct {
+	mock.Mock
+}
+
+type MockRoleStore struct {
+	mock.Mock
+}
+
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:          &MockPostStore{},
+		Users:          &MockUserStore{},
+		Comments:       &MockCommentStore{},
+		Reactions:      &MockReactionStore{},
+		Bookmarks:      &MockBookmarkStore{},
+		Followers:      &MockFollowerStore{},
+		Blocks:         &MockBlockStore{},
+		Tokens:         &MockTokenStore{},
+		Audit:          &MockAuditStore{},
+		MFA:            &MockMFAStore{},
+		APIKeys:        &MockAPIKeyStore{},
+		Identities:     &MockIdentityStore{},
+		Roles:          &MockRoleStore{},
+		FollowRequests: &MockFollowRequestStore{},
 	}
 }
 
@@ -22,8 +87,37 @@ func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, u *User) error {
 	return nil
 }
 
+// MockUnknownUserID is the ID of the only user MockUserStore doesn't know.
+const MockUnknownUserID = 404
+
+const (
+	// MockAdminUserID is the ID of a user holding every permission.
+	MockAdminUserID = 100
+	// MockBannedUserID is the ID of a permanently banned user.
+	MockBannedUserID = 403
+	// MockSignedOutUserID is the ID of a user whose sessions are revoked
+	// until an hour from now.
+	MockSignedOutUserID = 401
+	// MockBlockedUserID is the ID of a user blocked by everybody else.
+	MockBlockedUserID = 402
+)
+
 func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
-	return &User{ID: 1}, nil
+	switch userID {
+	case MockUnknownUserID:
+		return nil, ErrNotFound
+	case MockAdminUserID:
+		return &User{ID: userID, Role: Role{Name: "admin", Permissions: []string{
+			PermReadUsers, PermUpdateUserRole, PermBanUsers, PermLogoutUsers,
+		}}}, nil
+	case MockBannedUserID:
+		return &User{ID: userID, Ban: &Ban{Reason: "spam"}}, nil
+	case MockSignedOutUserID:
+		revokedAt := time.Now().Add(time.Hour)
+		return &User{ID: userID, SessionsRevokedAt: &revokedAt}, nil
+	}
+
+	return &User{ID: userID}, nil
 }
 
 func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
@@ -38,6 +132,354 @@ func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
 	return nil
 }
 
+// MockPassword is the password of every user returned by MockUserStore.
+const MockPassword = "password"
+
+// MockLockedEmail is the email of a user locked out after failed logins.
+const MockLockedEmail = "locked@example.com"
+
 func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	return &User{ID: 1}, nil
+	user := &User{ID: 1, Email: email}
+	if err := user.Password.Set(MockPassword); err != nil {
+		return nil, err
+	}
+
+	if email == MockLockedEmail {
+		lockedUntil := time.Now().Add(time.Hour)
+		user.LockedUntil = &lockedUntil
+	}
+
+	return user, nil
+}
+
+func (m *MockUserStore) SetPrivate(ctx context.Context, userID int64, private bool) error {
+	return nil
+}
+
+func (m *MockUserStore) RegisterFailedLogin(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (m *MockUserStore) ResetFailedLogins(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockUserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	args := m.Called(token)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserStore) ResendInvitation(ctx context.Context, email, token string, invitationExp time.Duration) (*User, error) {
+	return &User{Email: email}, nil
+}
+
+func (m *MockUserStore) PurgeExpiredInvitations(ctx context.Context, grace time.Duration) (int64, error) {
+	args := m.Called(grace)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserStore) List(ctx context.Context, q PaginatedUsersQuery) ([]User, *Cursor, error) {
+	return []User{}, nil, nil
+}
+
+func (m *MockUserStore) SetRole(ctx context.Context, userID int64, role string) error {
+	return mockModeration(userID)
+}
+
+func (m *MockUserStore) Ban(ctx context.Context, userID int64, reason string, until *time.Time) error {
+	return mockModeration(userID)
+}
+
+func (m *MockUserStore) Unban(ctx context.Context, userID int64) error {
+	return mockModeration(userID)
+}
+
+func (m *MockUserStore) RevokeSessions(ctx context.Context, userID int64) error {
+	return mockModeration(userID)
+}
+
+func (m *MockUserStore) Deactivate(ctx context.Context, userID int64) error {
+	return mockModeration(userID)
+}
+
+func (m *MockUserStore) Reactivate(ctx context.Context, userID int64) error {
+	return mockModeration(userID)
+}
+
+// mockModeration fails moderations of MockUnknownUserID like the store does.
+func mockModeration(userID int64) error {
+	if userID == MockUnknownUserID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, c *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	return &Comment{ID: commentID}, nil
+}
+
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID int64, cq PaginatedCommentsQuery) ([]Comment, *Cursor, error) {
+	args := m.Called(postID, cq.After)
+
+	comments, _ := args.Get(0).([]Comment)
+	next, _ := args.Get(1).(*Cursor)
+
+	return comments, next, args.Error(2)
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, c *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, commentID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, postID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *Post) error {
+	return nil
+}
+
+// MockDeactivatedAuthorPostID is the ID of a post whose author
+// MockUserStore doesn't return, like a deactivated one.
+const MockDeactivatedAuthorPostID = 405
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+	if postID == MockDeactivatedAuthorPostID {
+		return &Post{ID: postID, UserID: MockUnknownUserID}, nil
+	}
+
+	return &Post{ID: postID}, nil
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, *Cursor, error) {
+	args := m.Called(userID)
+
+	feed, _ := args.Get(0).([]PostWithMetadata)
+	next, _ := args.Get(1).(*Cursor)
+
+	return feed, next, args.Error(2)
+}
+
+func (m *MockReactionStore) Add(ctx context.Context, postID, userID int64, kind string) error {
+	return m.Called(postID, userID, kind).Error(0)
+}
+
+func (m *MockReactionStore) Remove(ctx context.Context, postID, userID int64, kind string) error {
+	return m.Called(postID, userID, kind).Error(0)
+}
+
+func (m *MockReactionStore) GetByPostIDs(ctx context.Context, postIDs []int64, viewerID int64) (map[int64]Reactions, error) {
+	return map[int64]Reactions{}, nil
+}
+
+func (m *MockBookmarkStore) Add(ctx context.Context, userID, postID int64) error {
+	return m.Called(userID, postID).Error(0)
+}
+
+func (m *MockBookmarkStore) Remove(ctx context.Context, userID, postID int64) error {
+	return m.Called(userID, postID).Error(0)
+}
+
+func (m *MockBookmarkStore) GetByUserID(ctx context.Context, userID int64, q PaginatedQuery) ([]BookmarkedPost, *Cursor, error) {
+	args := m.Called(userID, q.After)
+
+	bookmarks, _ := args.Get(0).([]BookmarkedPost)
+	next, _ := args.Get(1).(*Cursor)
+
+	return bookmarks, next, args.Error(2)
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowers(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	return []FollowedUser{}, nil, nil
+}
+
+func (m *MockFollowerStore) GetFollowing(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	return []FollowedUser{}, nil, nil
+}
+
+func (m *MockFollowerStore) Counts(ctx context.Context, userID int64) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (m *MockFollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockBlockStore) Block(ctx context.Context, blockerID, userID int64) error {
+	if userID == MockUnknownUserID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockBlockStore) Unblock(ctx context.Context, blockerID, userID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) Mute(ctx context.Context, muterID, userID int64) error {
+	if userID == MockUnknownUserID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockBlockStore) Unmute(ctx context.Context, muterID, userID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockFollowRequestStore) Create(ctx context.Context, requesterID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowRequestStore) GetIncoming(ctx context.Context, userID int64, q PaginatedQuery) ([]FollowedUser, *Cursor, error) {
+	return []FollowedUser{}, nil, nil
+}
+
+func (m *MockFollowRequestStore) Approve(ctx context.Context, userID, requesterID int64) error {
+	if requesterID == MockBlockedUserID {
+		return ErrBlocked
+	}
+	return nil
+}
+
+func (m *MockFollowRequestStore) Reject(ctx context.Context, userID, requesterID int64) error {
+	return nil
+}
+
+func (m *MockTokenStore) CreateRefreshToken(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockTokenStore) RotateRefreshToken(ctx context.Context, token, next string, exp time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockTokenStore) RevokeRefreshToken(ctx context.Context, userID int64, token string) error {
+	return nil
+}
+
+func (m *MockTokenStore) RevokeAllRefreshTokens(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockTokenStore) RevokeAccessToken(ctx context.Context, jti string, expiry time.Time) error {
+	return nil
+}
+
+func (m *MockTokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return false, nil
+}
+
+func (m *MockTokenStore) PurgeExpired(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuditStore) Log(ctx context.Context, entry *AuditEntry) error {
+	return nil
+}
+
+func (m *MockMFAStore) Enroll(ctx context.Context, userID int64, secret []byte, recoveryCodes []string) error {
+	return nil
+}
+
+func (m *MockMFAStore) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	args := m.Called(userID)
+
+	totp, _ := args.Get(0).(*TOTP)
+	return totp, args.Error(1)
+}
+
+func (m *MockMFAStore) Enable(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockMFAStore) Disable(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockMFAStore) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	return true, nil
+}
+
+func (m *MockMFAStore) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	return false, nil
+}
+
+// The keys the mock store knows, both belong to user 25.
+const (
+	MockAPIKey      = "gsk_mockreadmockreadmock"
+	MockWriteAPIKey = "gsk_mockwritemockwritemo"
+)
+
+func (m *MockAPIKeyStore) Create(ctx context.Context, key *APIKey, plainKey string) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	return []APIKey{}, nil
+}
+
+func (m *MockAPIKeyStore) GetByKey(ctx context.Context, plainKey string) (*APIKey, error) {
+	switch plainKey {
+	case MockAPIKey:
+		return &APIKey{ID: 1, UserID: 25, Scopes: []string{"read"}}, nil
+	case MockWriteAPIKey:
+		return &APIKey{ID: 2, UserID: 25, Scopes: []string{"read", "write"}}, nil
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+func (m *MockAPIKeyStore) Touch(ctx context.Context, keyID int64) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) Delete(ctx context.Context, userID, keyID int64) error {
+	return nil
+}
+
+func (m *MockIdentityStore) GetUser(ctx context.Context, provider, subject string) (*User, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockIdentityStore) Link(ctx context.Context, userID int64, provider, subject string) error {
+	return nil
+}
+
+func (m *MockIdentityStore) CreateUser(ctx context.Context, user *User, provider, subject string) error {
+	user.ID = 1
+	return nil
+}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
+	return &Role{Name: name}, nil
 }
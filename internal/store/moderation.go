@@ -0,0 +1,260 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+// Ban keeps a user from using the API. A ban without Until is permanent.
+type Ban struct {
+	Reason   string     `json:"reason"`
+	BannedAt time.Time  `json:"banned_at"`
+	Until    *time.Time `json:"until"`
+}
+
+// IsBanned reports whether the user is currently banned; temporary bans
+// simply run out.
+func (u *User) IsBanned() bool {
+	return u.Ban != nil && (u.Ban.Until == nil || u.Ban.Until.After(time.Now()))
+}
+
+// banColumns scans the nullable ban columns of the users table.
+type banColumns struct {
+	at     *time.Time
+	until  *time.Time
+	reason string
+}
+
+func (c banColumns) ban() *Ban {
+	if c.at == nil {
+		return nil
+	}
+
+	return &Ban{Reason: c.reason, BannedAt: *c.at, Until: c.until}
+}
+
+// List returns the users matching the filters of q, newest first, and the
+// cursor of the next page (nil on the last page).
+func (u *UserStore) List(ctx context.Context, q PaginatedUsersQuery) ([]User, *Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	afterCreatedAt, afterID := keysetAfter(q.After)
+
+	query := `
+		SELECT users.id, username, email, created_at, is_active, is_private,
+		banned_at, banned_until, coalesce(ban_reason, ''),
+		roles.id, roles.name, roles.level, roles.description
+		FROM users
+		JOIN roles ON (users.role_id = roles.id)
+		WHERE
+			($2 = '' OR username ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%') AND
+			($3 = '' OR roles.name = $3) AND
+			(
+				$4 = '' OR
+				($4 = 'active' AND is_active AND (banned_at IS NULL OR banned_until < NOW())) OR
+				($4 = 'inactive' AND NOT is_active) OR
+				($4 = 'banned' AND banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+			) AND
+			($5::timestamptz IS NULL OR (users.created_at, users.id) < ($5::timestamptz, $6))
+		ORDER BY users.created_at DESC, users.id DESC
+		LIMIT $1
+	`
+
+	// one extra row tells us whether there is a next page
+	rows, err := u.db.QueryContext(ctx, query, q.Limit+1, q.Search, q.Role, q.Status, afterCreatedAt, afterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		var (
+			user User
+			ban  banColumns
+		)
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.CreatedAt,
+			&user.IsActive,
+			&user.IsPrivate,
+			&ban.at,
+			&ban.until,
+			&ban.reason,
+			&user.Role.ID,
+			&user.Role.Name,
+			&user.Role.Level,
+			&user.Role.Description,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		user.RoleID = user.Role.ID
+		user.Ban = ban.ban()
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	users, next := nextPage(users, q.Limit, func(last User) Cursor {
+		return Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	})
+
+	return users, next, nil
+}
+
+func (u *UserStore) SetRole(ctx context.Context, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var roleID int64
+	err := u.db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, role).Scan(&roleID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return ErrRoleNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+		UPDATE users SET role_id = $1 WHERE id = $2
+	`
+
+	return u.execAffectingUser(ctx, query, roleID, userID)
+}
+
+// Ban bans a user and revokes their refresh tokens, so they can't get new
+// access tokens either.
+func (u *UserStore) Ban(ctx context.Context, userID int64, reason string, until *time.Time) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users SET banned_at = NOW(), banned_until = $1, ban_reason = $2
+			WHERE id = $3
+		`
+
+		res, err := tx.ExecContext(ctx, query, until, reason, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUserAffected(res); err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(ctx, tx, userID)
+	})
+}
+
+func (u *UserStore) Unban(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users SET banned_at = NULL, banned_until = NULL, ban_reason = NULL
+		WHERE id = $1
+	`
+
+	return u.execAffectingUser(ctx, query, userID)
+}
+
+// Deactivate disables an activated account until it is reactivated. Unlike
+// a pending invitation it is never purged, and like a ban it signs the user
+// out everywhere.
+func (u *UserStore) Deactivate(ctx context.Context, userID int64) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users
+			SET is_active = false, deactivated_at = coalesce(deactivated_at, NOW()), sessions_revoked_at = NOW()
+			WHERE id = $1 AND (is_active OR deactivated_at IS NOT NULL)
+		`
+
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUserAffected(res); err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(ctx, tx, userID)
+	})
+}
+
+// Reactivate enables a deactivated account again. Accounts that were never
+// activated must still confirm their invitation.
+func (u *UserStore) Reactivate(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users SET is_active = true, deactivated_at = NULL
+		WHERE id = $1 AND deactivated_at IS NOT NULL
+	`
+
+	return u.execAffectingUser(ctx, query, userID)
+}
+
+// RevokeSessions signs a user out everywhere: refresh tokens are revoked and
+// access tokens issued until now are no longer accepted.
+func (u *UserStore) RevokeSessions(ctx context.Context, userID int64) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users SET sessions_revoked_at = NOW() WHERE id = $1
+		`
+
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUserAffected(res); err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(ctx, tx, userID)
+	})
+}
+
+func (u *UserStore) execAffectingUser(ctx context.Context, query string, args ...any) error {
+	res, err := u.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return checkUserAffected(res)
+}
+
+func checkUserAffected(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
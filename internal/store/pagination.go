@@ -1,6 +1,7 @@
 package store
 
 import (
+	"database/sql"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,11 +11,20 @@ import (
 type PaginatedFeedQuery struct {
 	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
 	Offset int      `json:"offset" validate:"gte=0"`
-	Sort   string   `json:"sort" validate:"oneof=asc desc"`
+	Sort   string   `json:"sort" validate:"oneof=asc desc top hot"`
 	Tags   []string `json:"tags" validate:"max=5"`
 	Search string   `json:"search" validate:"max=100"`
 	Since  string   `json:"since"`
 	Until  string   `json:"until"`
+	Cursor string   `json:"cursor" validate:"max=256"`
+	// After is the decoded Cursor. When set it takes precedence over Offset.
+	After *Cursor `json:"-"`
+}
+
+// IsRanked reports whether the feed is ordered by engagement score rather
+// than by creation time.
+func (fq PaginatedFeedQuery) IsRanked() bool {
+	return fq.Sort == "top" || fq.Sort == "hot"
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -63,9 +73,106 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		fq.Until = parseTime(until)
 	}
 
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		fq.Cursor = cursor
+	}
+
 	return fq, nil
 }
 
+// PaginatedQuery pages through simple listings (bookmarks, followers, ...)
+// newest first.
+type PaginatedQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=20"`
+	Cursor string  `json:"cursor" validate:"max=256"`
+	After  *Cursor `json:"-"`
+}
+
+func (q PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		q.Cursor = cursor
+	}
+
+	return q, nil
+}
+
+// PaginatedUsersQuery filters the user listing of the admin API.
+type PaginatedUsersQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=100"`
+	Cursor string  `json:"cursor" validate:"max=256"`
+	After  *Cursor `json:"-"`
+	// Search matches part of the username or email.
+	Search string `json:"search" validate:"max=100"`
+	Role   string `json:"role" validate:"max=255"`
+	Status string `json:"status" validate:"omitempty,oneof=active inactive banned"`
+}
+
+func (uq PaginatedUsersQuery) Parse(r *http.Request) (PaginatedUsersQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return uq, err
+		}
+		uq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		uq.Cursor = cursor
+	}
+
+	uq.Search = qs.Get("search")
+	uq.Role = qs.Get("role")
+	uq.Status = qs.Get("status")
+
+	return uq, nil
+}
+
+type PaginatedCommentsQuery struct {
+	Limit    int     `json:"limit" validate:"gte=1,lte=20"`
+	Cursor   string  `json:"cursor" validate:"max=256"`
+	After    *Cursor `json:"-"`
+	MaxDepth int     `json:"-"`
+	// ViewerID hides comments of users blocking or blocked by the viewer.
+	ViewerID int64 `json:"-"`
+}
+
+func (cq PaginatedCommentsQuery) Parse(r *http.Request) (PaginatedCommentsQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return cq, err
+		}
+		cq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		cq.Cursor = cursor
+	}
+
+	return cq, nil
+}
+
 func parseTime(s string) string {
 	t, err := time.Parse(time.DateTime, s)
 	if err != nil {
@@ -74,3 +181,27 @@ func parseTime(s string) string {
 
 	return t.Format(time.DateTime)
 }
+
+// keysetAfter returns the query arguments of a keyset cursor: the
+// created_at of the last row, NULL on the first page, and its id.
+func keysetAfter(after *Cursor) (sql.NullString, int64) {
+	if after == nil || !after.IsKeyset() {
+		return sql.NullString{}, 0
+	}
+
+	return sql.NullString{String: after.CreatedAt, Valid: true}, after.ID
+}
+
+// nextPage cuts a page fetched with one extra row, which tells whether there
+// is a next page, down to limit and returns the cursor of the next page
+// (nil on the last page), pointing at the last row kept.
+func nextPage[T any](rows []T, limit int, cursor func(last T) Cursor) ([]T, *Cursor) {
+	if len(rows) <= limit {
+		return rows, nil
+	}
+
+	rows = rows[:limit]
+	next := cursor(rows[len(rows)-1])
+
+	return rows, &next
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextPage(t *testing.T) {
+	cursor := func(last int) Cursor { return Cursor{ID: int64(last)} }
+
+	tests := []struct {
+		name     string
+		rows     []int
+		wantRows []int
+		wantNext *Cursor
+	}{
+		{"empty page", []int{}, []int{}, nil},
+		{"short last page", []int{1, 2}, []int{1, 2}, nil},
+		{"full last page", []int{1, 2, 3}, []int{1, 2, 3}, nil},
+		{"page with an extra row", []int{1, 2, 3, 4}, []int{1, 2, 3}, &Cursor{ID: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, next := nextPage(tt.rows, 3, cursor)
+
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("Expected rows %v. Got %v", tt.wantRows, rows)
+			}
+			if !reflect.DeepEqual(next, tt.wantNext) {
+				t.Errorf("Expected next cursor %+v. Got %+v", tt.wantNext, next)
+			}
+		})
+	}
+}
@@ -19,45 +19,109 @@ type Post struct {
 	Version   int       `json:"version"`
 	Comments  []Comment `json:"comments"`
 	User      User      `json:"user"`
+	Reactions Reactions `json:"reactions"`
 }
 
 type PostWithMetadata struct {
 	Post
-	CommentCount int `json:"comment_count"`
+	CommentCount int     `json:"comment_count"`
+	Score        float64 `json:"score"`
 }
 
+// Ranked feeds order posts by (engagement + 1) / (age in hours + 2) ^ gravity,
+// where engagement is the number of comments plus the number of reactions.
+// "top" decays slowly and favours engagement, "hot" decays fast and favours
+// what is new and being talked about right now.
+const (
+	topFeedGravity = 0.8
+	hotFeedGravity = 1.8
+)
+
 type PostStore struct {
 	db *sql.DB
 }
 
-func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+// GetUserFeed returns a page of the user's feed and the cursor of the next
+// page (nil on the last page). Keyset paging via fq.After is preferred;
+// Offset is kept for older clients and ignored once a cursor is given.
+// Ranked feeds (sort=top/hot) have no stable key, so their cursor carries
+// an offset instead, along with the time the first page was ranked at:
+// later pages score posts as of then and leave out newer posts, so they
+// don't shift as time passes. Posts of blocked and muted users are never
+// shown.
+func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, *Cursor, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
+	var (
+		afterCreatedAt sql.NullString
+		afterID        int64
+		rankedAt       sql.NullString
+		offset         = fq.Offset
+		ranked         = fq.IsRanked()
+	)
+	if fq.After != nil {
+		offset = fq.After.Offset
+		if ranked && fq.After.RankedAt != "" {
+			rankedAt = sql.NullString{String: fq.After.RankedAt, Valid: true}
+		}
+		if !ranked && fq.After.IsKeyset() {
+			afterCreatedAt, afterID = keysetAfter(fq.After)
+			offset = 0
+		}
+	}
+
+	// rows past the cursor in the direction of the sort
+	cmp := "<"
+	if fq.Sort == "asc" {
+		cmp = ">"
+	}
+
+	orderBy := "p.created_at " + fq.Sort + ", p.id " + fq.Sort
+	gravity := hotFeedGravity
+	switch fq.Sort {
+	case "top":
+		orderBy = "score DESC, p.created_at DESC, p.id DESC"
+		gravity = topFeedGravity
+	case "hot":
+		orderBy = "score DESC, p.created_at DESC, p.id DESC"
+	}
+
 	query := `
 		select p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
 		u.username,
-		count (c.id) AS comments_count
+		count (c.id) AS comments_count,
+		(count (c.id) + (SELECT count(*) FROM reactions r WHERE r.post_id = p.id) + 1) /
+			power(extract(epoch FROM coalesce($9::timestamptz, now()) - p.created_at) / 3600 + 2, $8) AS score,
+		coalesce($9::timestamptz, now()) AS ranked_at
 		from posts p 
 		LEFT JOIN comments c ON c.post_id = p.id
 		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
 		WHERE
-			f.user_id = $1 AND
+			($9::timestamptz IS NULL OR p.created_at <= $9::timestamptz) AND
+			(p.user_id = $1 OR p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1)) AND
+			` + notBlockedSQL("$1", "p.user_id") + ` AND
+			NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $1 AND m.muted_id = p.user_id) AND
 			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
+			(p.tags @> $5 OR $5 = '{}') AND
+			($6::timestamptz IS NULL OR (p.created_at, p.id) ` + cmp + ` ($6::timestamptz, $7))
 		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
+		ORDER BY ` + orderBy + `
 		LIMIT $2 OFFSET $3
 	`
-	
-	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+
+	// one extra row tells us whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit+1, offset, fq.Search, pq.Array(fq.Tags), afterCreatedAt, afterID, gravity, rankedAt)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var feed []PostWithMetadata
+	var (
+		feed []PostWithMetadata
+		// the same for every row, now() is the start of the transaction
+		rankedAtNow string
+	)
 
 	for rows.Next() {
 		var p PostWithMetadata
@@ -71,15 +135,27 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 			pq.Array(&p.Tags),
 			&p.User.Username,
 			&p.CommentCount,
+			&p.Score,
+			&rankedAtNow,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		feed = append(feed, p)
 	}
-	return feed, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	feed, next := nextPage(feed, fq.Limit, func(last PostWithMetadata) Cursor {
+		if ranked {
+			return Cursor{Offset: offset + fq.Limit, RankedAt: rankedAtNow}
+		}
+		return Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	})
 
+	return feed, next, nil
 }
 
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
@@ -109,9 +185,11 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 	defer cancel()
 
 	query := `
-		select id, user_id, title, content, created_at, updated_at, tags, version
-		from posts 
-		where id = $1
+		select p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, p.tags, p.version,
+		u.id, u.username, u.is_private
+		from posts p
+		JOIN users u ON u.id = p.user_id
+		where p.id = $1
 	`
 
 	var post Post
@@ -124,6 +202,9 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		&post.UpdatedAt,
 		pq.Array(&post.Tags),
 		&post.Version,
+		&post.User.ID,
+		&post.User.Username,
+		&post.User.IsPrivate,
 	)
 	if err != nil {
 		switch {
@@ -137,6 +218,8 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
 	return &post, nil
 }
 
+// Delete removes a post. Its reactions and the bookmarks pointing at it are
+// removed along with it by the ON DELETE CASCADE foreign keys.
 func (s *PostStore) Delete(ctx context.Context, postID int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// ReactionKinds are the reactions a user can leave on a post.
+var ReactionKinds = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+type ReactionCount struct {
+	Count       int  `json:"count"`
+	ReactedByMe bool `json:"reacted_by_me"`
+}
+
+// Reactions maps a reaction kind to how often it was left on a post.
+type Reactions map[string]ReactionCount
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+func IsReactionKind(kind string) bool {
+	for _, k := range ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ReactionStore) Add(ctx context.Context, postID, userID int64, kind string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO reactions (post_id, user_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query, postID, userID, kind)
+	return err
+}
+
+func (s *ReactionStore) Remove(ctx context.Context, postID, userID int64, kind string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		DELETE FROM reactions
+		WHERE post_id = $1 AND user_id = $2 AND kind = $3
+	`
+
+	_, err := s.db.ExecContext(ctx, query, postID, userID, kind)
+	return err
+}
+
+// GetByPostIDs returns the reactions of every given post as seen by viewerID.
+// Posts without reactions are missing from the map.
+func (s *ReactionStore) GetByPostIDs(ctx context.Context, postIDs []int64, viewerID int64) (map[int64]Reactions, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT post_id, kind, count(*), bool_or(user_id = $2)
+		FROM reactions
+		WHERE post_id = ANY($1)
+		GROUP BY post_id, kind
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(postIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make(map[int64]Reactions, len(postIDs))
+
+	for rows.Next() {
+		var (
+			postID int64
+			kind   string
+			rc     ReactionCount
+		)
+		if err := rows.Scan(&postID, &kind, &rc.Count, &rc.ReactedByMe); err != nil {
+			return nil, err
+		}
+
+		if reactions[postID] == nil {
+			reactions[postID] = Reactions{}
+		}
+		reactions[postID][kind] = rc
+	}
+
+	return reactions, rows.Err()
+}
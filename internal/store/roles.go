@@ -3,24 +3,59 @@ package store
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// Permissions granted to roles. Owners may always update and delete their
+// own content, the ":any" permissions extend that to content of others.
+const (
+	PermUpdateAnyPost    = "posts:update:any"
+	PermDeleteAnyPost    = "posts:delete:any"
+	PermUpdateAnyComment = "comments:update:any"
+	PermDeleteAnyComment = "comments:delete:any"
+	PermReadUsers        = "users:read:any"
+	PermUpdateUserRole   = "users:update:role"
+	PermBanUsers         = "users:ban"
+	PermLogoutUsers      = "users:logout"
 )
 
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       string `json:"level"`
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Level       int      `json:"level"`
+	Permissions []string `json:"permissions"`
 }
 
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePermissionsSQL selects the permission names of the role with the id
+// in column as an array.
+const rolePermissionsSQL = `
+	ARRAY(
+		SELECT p.name FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE rp.role_id = roles.id
+		ORDER BY p.name
+	)`
+
 type RoleStore struct {
 	db *sql.DB
 }
 
 func (r *RoleStore) GetByName(ctx context.Context, slug string) (*Role, error) {
-	query := `SELECT id, name, description, level FROM roles WHERE name = $1`
+	query := `SELECT id, name, description, level, ` + rolePermissionsSQL + ` FROM roles WHERE name = $1`
 
 	role := &Role{}
-	err := r.db.QueryRowContext(ctx, query, slug).Scan(&role.ID, &role.Name, &role.Description, &role.Level)
+	err := r.db.QueryRowContext(ctx, query, slug).Scan(&role.ID, &role.Name, &role.Description, &role.Level, pq.Array(&role.Permissions))
 	if err != nil {
 		return nil, err
 	}
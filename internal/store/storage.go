@@ -10,6 +10,7 @@ import (
 var (
 	ErrNotFound = errors.New("resource not found")
 	ErrConflict = errors.New("resource already exists")
+	ErrBlocked  = errors.New("user is blocked")
 
 	QueryTimeoutDuration = time.Second * 5
 )
@@ -20,7 +21,7 @@ type Storage struct {
 		Delete(context.Context, int64) error
 		Update(context.Context, *Post) error
 		GetByID(context.Context, int64) (*Post, error)
-		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, *Cursor, error)
 	}
 	Users interface {
 		Create(context.Context, *sql.Tx, *User) error
@@ -29,26 +30,112 @@ type Storage struct {
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
 		GetByEmail(context.Context, string) (*User, error)
+		SetPrivate(ctx context.Context, userID int64, private bool) error
+		RegisterFailedLogin(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) (bool, error)
+		ResetFailedLogins(context.Context, int64) error
+		CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error
+		ResetPassword(ctx context.Context, token, newPassword string) (int64, error)
+		ResendInvitation(ctx context.Context, email, token string, invitationExp time.Duration) (*User, error)
+		PurgeExpiredInvitations(ctx context.Context, grace time.Duration) (int64, error)
+		List(context.Context, PaginatedUsersQuery) ([]User, *Cursor, error)
+		SetRole(ctx context.Context, userID int64, role string) error
+		Ban(ctx context.Context, userID int64, reason string, until *time.Time) error
+		Unban(context.Context, int64) error
+		RevokeSessions(context.Context, int64) error
+		Deactivate(context.Context, int64) error
+		Reactivate(context.Context, int64) error
 	}
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		Create(context.Context, *Comment) error
+		GetByID(context.Context, int64) (*Comment, error)
+		GetByPostID(context.Context, int64, PaginatedCommentsQuery) ([]Comment, *Cursor, error)
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64) error
 	}
 	Followers interface {
 		Follow(ctx context.Context, followerID, userID int64) error
 		Unfollow(ctx context.Context, followerID, userID int64) error
+		GetFollowers(context.Context, int64, PaginatedQuery) ([]FollowedUser, *Cursor, error)
+		GetFollowing(context.Context, int64, PaginatedQuery) ([]FollowedUser, *Cursor, error)
+		Counts(ctx context.Context, userID int64) (followers int, following int, err error)
+		IsFollowing(ctx context.Context, followerID, userID int64) (bool, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+	FollowRequests interface {
+		Create(ctx context.Context, requesterID, userID int64) error
+		GetIncoming(context.Context, int64, PaginatedQuery) ([]FollowedUser, *Cursor, error)
+		Approve(ctx context.Context, userID, requesterID int64) error
+		Reject(ctx context.Context, userID, requesterID int64) error
+	}
+	Blocks interface {
+		Block(ctx context.Context, blockerID, userID int64) error
+		Unblock(ctx context.Context, blockerID, userID int64) error
+		Mute(ctx context.Context, muterID, userID int64) error
+		Unmute(ctx context.Context, muterID, userID int64) error
+		IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+	}
+	Bookmarks interface {
+		Add(ctx context.Context, userID, postID int64) error
+		Remove(ctx context.Context, userID, postID int64) error
+		GetByUserID(context.Context, int64, PaginatedQuery) ([]BookmarkedPost, *Cursor, error)
+	}
+	Tokens interface {
+		CreateRefreshToken(ctx context.Context, userID int64, token string, exp time.Duration) error
+		RotateRefreshToken(ctx context.Context, token, next string, exp time.Duration) (int64, error)
+		RevokeRefreshToken(ctx context.Context, userID int64, token string) error
+		RevokeAllRefreshTokens(context.Context, int64) error
+		RevokeAccessToken(ctx context.Context, jti string, expiry time.Time) error
+		IsAccessTokenRevoked(context.Context, string) (bool, error)
+		PurgeExpired(context.Context) (int64, error)
+	}
+	Audit interface {
+		Log(context.Context, *AuditEntry) error
+	}
+	APIKeys interface {
+		Create(ctx context.Context, key *APIKey, plainKey string) error
+		GetByUserID(context.Context, int64) ([]APIKey, error)
+		GetByKey(context.Context, string) (*APIKey, error)
+		Touch(context.Context, int64) error
+		Delete(ctx context.Context, userID, keyID int64) error
+	}
+	Identities interface {
+		GetUser(ctx context.Context, provider, subject string) (*User, error)
+		Link(ctx context.Context, userID int64, provider, subject string) error
+		CreateUser(ctx context.Context, user *User, provider, subject string) error
+	}
+	MFA interface {
+		Enroll(ctx context.Context, userID int64, secret []byte, recoveryCodes []string) error
+		Get(context.Context, int64) (*TOTP, error)
+		Enable(context.Context, int64) error
+		Disable(context.Context, int64) error
+		UseStep(ctx context.Context, userID, step int64) (bool, error)
+		UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error)
+	}
+	Reactions interface {
+		Add(ctx context.Context, postID, userID int64, kind string) error
+		Remove(ctx context.Context, postID, userID int64, kind string) error
+		GetByPostIDs(ctx context.Context, postIDs []int64, viewerID int64) (map[int64]Reactions, error)
+	}
 }
 
 func NewPostgresStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:          &PostStore{db},
+		Users:          &UserStore{db},
+		Comments:       &CommentStore{db},
+		Followers:      &FollowerStore{db},
+		Roles:          &RoleStore{db},
+		Reactions:      &ReactionStore{db},
+		Bookmarks:      &BookmarkStore{db},
+		Blocks:         &BlockStore{db},
+		Tokens:         &TokenStore{db},
+		Audit:          &AuditStore{db},
+		MFA:            &MFAStore{db},
+		APIKeys:        &APIKeyStore{db},
+		Identities:     &IdentityStore{db},
+		FollowRequests: &FollowRequestStore{db},
 	}
 }
 
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrTokenReused is returned when an already rotated refresh token is used
+// again. That only happens when a token was stolen, so every refresh token
+// of the user is revoked.
+var ErrTokenReused = errors.New("refresh token has already been used")
+
+// TokenStore keeps refresh tokens (hashed, like invitation tokens) and the
+// denylist of revoked access tokens.
+type TokenStore struct {
+	db *sql.DB
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *TokenStore) CreateRefreshToken(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO refresh_tokens (token, user_id, expiry)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, hashToken(token), userID, time.Now().Add(exp))
+	return err
+}
+
+// RotateRefreshToken consumes token and stores next in its place, returning
+// the user the token belongs to.
+func (s *TokenStore) RotateRefreshToken(ctx context.Context, token, next string, exp time.Duration) (int64, error) {
+	var (
+		userID int64
+		reused bool
+	)
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			SELECT user_id, expiry, revoked_at FROM refresh_tokens
+			WHERE token = $1
+			FOR UPDATE
+		`
+
+		var (
+			expiry    time.Time
+			revokedAt sql.NullTime
+		)
+		err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(&userID, &expiry, &revokedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if revokedAt.Valid {
+			reused = true
+			return revokeRefreshTokens(ctx, tx, userID)
+		}
+
+		if time.Now().After(expiry) {
+			return ErrNotFound
+		}
+
+		query = `
+			UPDATE refresh_tokens SET revoked_at = NOW() WHERE token = $1
+		`
+
+		if _, err := tx.ExecContext(ctx, query, hashToken(token)); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO refresh_tokens (token, user_id, expiry)
+			VALUES ($1, $2, $3)
+		`
+
+		_, err = tx.ExecContext(ctx, query, hashToken(next), userID, time.Now().Add(exp))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if reused {
+		return 0, ErrTokenReused
+	}
+
+	return userID, nil
+}
+
+func (s *TokenStore) RevokeRefreshToken(ctx context.Context, userID int64, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE token = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	_, err := s.db.ExecContext(ctx, query, hashToken(token), userID)
+	return err
+}
+
+func (s *TokenStore) RevokeAllRefreshTokens(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return revokeRefreshTokens(ctx, tx, userID)
+	})
+}
+
+func revokeRefreshTokens(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+// RevokeAccessToken denylists the access token with the given jti until it
+// expires on its own.
+func (s *TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiry time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expiry)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query, jti, expiry)
+	return err
+}
+
+func (s *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	`
+
+	var revoked bool
+	err := s.db.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// PurgeExpired deletes the refresh tokens and revoked access tokens past
+// their expiry, which no request can present anymore, and returns how many
+// were deleted. Reusing a rotated refresh token that expired is then
+// rejected like any unknown token instead of revoking its successors.
+func (s *TokenStore) PurgeExpired(ctx context.Context) (int64, error) {
+	var purged int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		for _, query := range []string{
+			`DELETE FROM refresh_tokens WHERE expiry < NOW()`,
+			`DELETE FROM revoked_tokens WHERE expiry < NOW()`,
+		} {
+			res, err := tx.ExecContext(ctx, query)
+			if err != nil {
+				return err
+			}
+
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			purged += rows
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
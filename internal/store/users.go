@@ -8,6 +8,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -23,8 +24,17 @@ type User struct {
 	Password  password `json:"-"`
 	CreatedAt string   `json:"created_at"`
 	IsActive  bool     `json:"is_active"`
+	IsPrivate bool     `json:"is_private"`
 	RoleID    int64    `json:"role_id"`
 	Role      Role     `json:"role"`
+
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	TOTPEnabled         bool       `json:"-"`
+	// Ban is nil unless a moderator banned the user
+	Ban *Ban `json:"-"`
+	// SessionsRevokedAt invalidates every token issued before it
+	SessionsRevokedAt *time.Time `json:"-"`
 }
 
 type password struct {
@@ -48,6 +58,27 @@ func (p *password) Set(text string) error {
 	return nil
 }
 
+// dummyHash is compared against when there is no real hash, so that a login
+// for an unknown email takes as long as one with a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("gophersocial"), bcrypt.DefaultCost)
+
+// Compare reports whether text matches the stored hash. bcrypt compares in
+// constant time.
+func (p *password) Compare(text string) bool {
+	if p.hash == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(text))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword(p.hash, []byte(text)) == nil
+}
+
+// IsLocked reports whether logins are temporarily refused after too many
+// failed attempts.
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
+}
+
 func (u *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -86,12 +117,16 @@ func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	defer cancel()
 
 	query := `
-		select users.id, username, email, password, created_at, roles.*
+		select users.id, username, email, password, created_at, is_private, failed_login_attempts, locked_until,
+		banned_at, banned_until, coalesce(ban_reason, ''), sessions_revoked_at,
+		roles.id, roles.name, roles.level, roles.description, ` + rolePermissionsSQL + `
 		from users
 		JOIN roles ON (users.role_id = roles.id)
 		where users.id = $1 AND is_active = true 
 	`
 
+	var ban banColumns
+
 	user := &User{}
 	err := u.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
@@ -99,10 +134,18 @@ func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.IsPrivate,
+		&user.FailedLoginAttempts,
+		&user.LockedUntil,
+		&ban.at,
+		&ban.until,
+		&ban.reason,
+		&user.SessionsRevokedAt,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
 		&user.Role.Description,
+		pq.Array(&user.Role.Permissions),
 	)
 	if err != nil {
 		switch err {
@@ -113,6 +156,8 @@ func (u *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		}
 	}
 
+	user.Ban = ban.ban()
+
 	return user, nil
 }
 
@@ -153,6 +198,97 @@ func (u *UserStore) Activate(ctx context.Context, token string) error {
 	})
 }
 
+// ResendInvitation replaces the invitations of the not yet activated user
+// with the given email by a new one.
+func (u *UserStore) ResendInvitation(ctx context.Context, email, token string, invitationExp time.Duration) (*User, error) {
+	user := &User{}
+
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			SELECT id, username, email, created_at FROM users
+			WHERE email = $1 AND is_active = false AND deactivated_at IS NULL
+			FOR UPDATE
+		`
+
+		err := tx.QueryRowContext(ctx, query, email).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := u.deleteUserFromInvitations(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return u.createUserInvitation(ctx, tx, token, invitationExp, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// PurgeExpiredInvitations deletes the users that never activated their
+// account and whose invitations expired more than grace ago, freeing their
+// username and email. Deactivated accounts are kept. It returns how many users were deleted.
+func (u *UserStore) PurgeExpiredInvitations(ctx context.Context, grace time.Duration) (int64, error) {
+	return u.purgeInvitationsExpiredBefore(ctx, time.Now().Add(-grace))
+}
+
+// purgeInvitationsExpiredBefore deletes the inactive users created before
+// cutoff that have no invitation expiring after it.
+func (u *UserStore) purgeInvitationsExpiredBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			DELETE FROM users u
+			WHERE u.is_active = false AND u.deactivated_at IS NULL AND u.created_at < $1 AND
+				NOT EXISTS (SELECT 1 FROM user_invitations ui WHERE ui.user_id = u.id AND ui.expiry > $1)
+		`
+
+		res, err := tx.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			return err
+		}
+
+		purged, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		// invitations have no foreign key, drop the ones left behind
+		query = `
+			DELETE FROM user_invitations ui
+			WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = ui.user_id)
+		`
+
+		_, err = tx.ExecContext(ctx, query)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
 func (u *UserStore) Delete(ctx context.Context, userID int64) error {
 	return withTx(u.db, ctx, func(tx *sql.Tx) error {
 		if err := u.delete(ctx, tx, userID); err != nil {
@@ -171,10 +307,13 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	query := `SELECT id, username, email, password, created_at FROM users
+	query := `SELECT id, username, email, password, created_at, failed_login_attempts, locked_until, totp_enabled,
+	banned_at, banned_until, coalesce(ban_reason, '') FROM users
 	WHERE email = $1 AND is_active = true 
 	`
 
+	var ban banColumns
+
 	user := &User{}
 	err := u.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
@@ -182,6 +321,12 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.FailedLoginAttempts,
+		&user.LockedUntil,
+		&user.TOTPEnabled,
+		&ban.at,
+		&ban.until,
+		&ban.reason,
 	)
 	if err != nil {
 		switch err {
@@ -192,9 +337,194 @@ func (u *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		}
 	}
 
+	user.Ban = ban.ban()
+
 	return user, nil
 }
 
+// SetPrivate makes an account private or public. Requests to follow a
+// public account aren't needed, so going public approves the pending ones.
+func (u *UserStore) SetPrivate(ctx context.Context, userID int64, private bool) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			UPDATE users SET is_private = $1 WHERE id = $2
+		`
+
+		if _, err := tx.ExecContext(ctx, query, private, userID); err != nil {
+			return err
+		}
+
+		if private {
+			return nil
+		}
+
+		query = `
+			WITH approved AS (
+				DELETE FROM follow_requests
+				WHERE user_id = $1
+				RETURNING user_id, requester_id
+			)
+			INSERT INTO followers (user_id, follower_id)
+			SELECT user_id, requester_id FROM approved
+			WHERE ` + notBlockedSQL("approved.user_id", "approved.requester_id") + `
+			ON CONFLICT DO NOTHING
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID)
+		return err
+	})
+}
+
+// RegisterFailedLogin counts a failed login. Once maxAttempts is reached the
+// account is locked for lockout and the counter starts over. It returns
+// whether this attempt locked the account.
+func (u *UserStore) RegisterFailedLogin(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END,
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3) ELSE locked_until END
+		WHERE id = $1
+		RETURNING failed_login_attempts = 0
+	`
+
+	var locked bool
+	err := u.db.QueryRowContext(ctx, query, userID, maxAttempts, lockout.Seconds()).Scan(&locked)
+	return locked, err
+}
+
+func (u *UserStore) ResetFailedLogins(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1
+	`
+
+	_, err := u.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// CreatePasswordReset stores the (hashed) reset token of a user, replacing
+// any reset that was requested before.
+func (u *UserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return withTx(u.db, ctx, func(tx *sql.Tx) error {
+		if err := u.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			insert into password_resets (token, user_id, expiry)
+			values ($1, $2, $3)
+		`
+
+		_, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ResetPassword consumes a reset token, sets the new password and returns
+// the ID of the user. Every session of the user is revoked and a pending
+// lockout lifted.
+func (u *UserStore) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	var userID int64
+
+	err := withTx(u.db, ctx, func(tx *sql.Tx) error {
+		user, err := u.getUserFromPasswordReset(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+
+		if err := user.Password.Set(newPassword); err != nil {
+			return err
+		}
+
+		if err := u.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		if err := u.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(ctx, tx, user.ID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (u *UserStore) getUserFromPasswordReset(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		select u.id, u.username, u.email, u.created_at
+		from users u
+		join password_resets pr ON u.id = pr.user_id
+		where pr.token = $1 and pr.expiry > $2
+	`
+
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+
+	user := &User{}
+	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (u *UserStore) updatePassword(ctx context.Context, tx *sql.Tx, user *User) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET password = $1, failed_login_attempts = 0, locked_until = NULL, sessions_revoked_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, user.Password.hash, user.ID)
+	return err
+}
+
+func (u *UserStore) deletePasswordResets(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		delete from password_resets where user_id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
 func (u *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, exp time.Duration, userID int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
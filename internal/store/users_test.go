@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestDB connects to the migrated Postgres database at TEST_DB_ADDR. The
+// tests write to it and may purge any of its users, so it must be a
+// throwaway database; they are skipped when it isn't set.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestPurgeExpiredInvitations(t *testing.T) {
+	db := newTestDB(t)
+	s := &UserStore{db}
+	ctx := context.Background()
+
+	// timestamps are stored with second precision
+	cutoff := time.Now().Truncate(time.Second).Add(-time.Hour)
+
+	createUser := func(t *testing.T, name string, active bool, createdAt time.Time, invitationExpiries ...time.Time) int64 {
+		t.Helper()
+
+		var userID int64
+		err := db.QueryRowContext(ctx, `
+			INSERT INTO users (username, email, password, is_active, created_at, role_id)
+			VALUES ($1, $2, '\x00', $3, $4, (SELECT id FROM roles WHERE name = 'user'))
+			RETURNING id
+		`, name, name+"@example.com", active, createdAt).Scan(&userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, expiry := range invitationExpiries {
+			_, err := db.ExecContext(ctx, `
+				INSERT INTO user_invitations (token, user_id, expiry) VALUES ($1, $2, $3)
+			`, []byte(fmt.Sprintf("%s-%d", name, i)), userID, expiry)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		t.Cleanup(func() {
+			db.Exec(`DELETE FROM user_invitations WHERE user_id = $1`, userID)
+			db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+		})
+
+		return userID
+	}
+
+	exists := func(t *testing.T, query string, userID int64) bool {
+		t.Helper()
+
+		var found bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (`+query+`)`, userID).Scan(&found); err != nil {
+			t.Fatal(err)
+		}
+		return found
+	}
+
+	suffix := time.Now().UnixNano()
+	name := func(s string) string { return fmt.Sprintf("purge-%s-%d", s, suffix) }
+
+	tests := []struct {
+		name   string
+		userID int64
+		purged bool
+	}{
+		{"invitation expired before the cutoff", createUser(t, name("expired"), false, cutoff.Add(-time.Hour), cutoff.Add(-time.Second)), true},
+		{"invitation expired at the cutoff", createUser(t, name("at-cutoff"), false, cutoff.Add(-time.Hour), cutoff), true},
+		{"invitation expired within the grace", createUser(t, name("in-grace"), false, cutoff.Add(-time.Hour), cutoff.Add(time.Second)), false},
+		{"resent invitation not expired yet", createUser(t, name("resent"), false, cutoff.Add(-time.Hour), cutoff.Add(-time.Hour), cutoff.Add(2*time.Hour)), false},
+		{"no invitation, created before the cutoff", createUser(t, name("old"), false, cutoff.Add(-time.Second)), true},
+		{"no invitation, created after the cutoff", createUser(t, name("new"), false, cutoff.Add(time.Second)), false},
+		{"activated account", createUser(t, name("active"), true, cutoff.Add(-time.Hour), cutoff.Add(-time.Hour)), false},
+	}
+
+	purged, err := s.purgeInvitationsExpiredBefore(ctx, cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want int64
+	for _, tt := range tests {
+		if tt.purged {
+			want++
+		}
+	}
+	// the database may hold other expired users
+	if purged < want {
+		t.Errorf("Expected at least %d purged users. Got %d", want, purged)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := exists(t, `SELECT 1 FROM users WHERE id = $1`, tt.userID)
+			if kept == tt.purged {
+				t.Errorf("Expected purged %v. Got %v", tt.purged, !kept)
+			}
+
+			invited := exists(t, `SELECT 1 FROM user_invitations WHERE user_id = $1`, tt.userID)
+			if tt.purged && invited {
+				t.Error("Expected the invitations of a purged user to be deleted")
+			}
+		})
+	}
+}